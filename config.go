@@ -4,21 +4,133 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"maps"
+	"math"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Configuration holds all settings for ProxyLauncher
 type Configuration struct {
-	Target         string
+	Target         string // candidate list as configured, the resolved executable after loadConfig
+	TargetSha256   string
+	SecurityPolicy string
 	ExtraArgs      string
 	ExtraArgsOrder string
 	HideTarget     bool
+	Pty            bool
+	Detach         bool
+	PidFile        string
+	PassFds        []int // inherited file descriptors passed on at the same numbers, Unix only
+
+	// Listener mode, launching the target for every connection instead of once
+	Listen         string // "tcp:<host>:<port>" or "unix:<path>"
+	MaxConnections int    // connections served at the same time, 0 for no limit
+
+	// Credentials of the target, applied on Unix only
+	RunAsUser           string
+	RunAsGroup          string
+	SupplementaryGroups []string // nil to use the user's own groups
+
+	// Namespace sandbox and Landlock filesystem restrictions for the target, Linux only
+	Sandbox SandboxConfig
+	FsAllow FsAllowConfig
+
+	// Process settings for the target, applied on Linux only
+	Limits      map[string]ResourceLimit
+	Nice        *int
+	Umask       *int
+	CPUAffinity CPUSet
+	IOClass     string
+	IOLevel     *int
+	OOMScoreAdj *int
+	Descendants string // what to do with processes the target leaves behind: "wait", "kill" or "ignore"
+
+	// Interpreter command lines by lowercase file extension, overriding the built-in ones
+	Interpreters map[string]string
+
+	// Routing rules, evaluated in order against the received arguments
+	Routes []Route
+
+	// Dependencies that must become ready before launching, in index order
+	WaitFor []WaitCondition
+
+	// Hooks
+	PreLaunch        []string
+	PreLaunchFailure string
+	PostExit         []string
+
+	// Output logging
+	StdoutLog           string
+	StderrLog           string
+	CombinedLog         string
+	OutputLogMode       string
+	OutputLogMaxSize    int64
+	OutputLogTimestamps bool
+
+	// Output filtering
+	StdoutFilters []outputFilter
+	StderrFilters []outputFilter
+
+	// Reruns of a target failing with certain exit codes
+	Retry RetryConfig
+
+	// Watchdogs killing the target, 0 if unset
+	IdleTimeout time.Duration
+	MaxRSS      int64 // Linux only
+
+	// Launcher log and resource usage report
+	LogFile     string
+	ReportUsage string
+}
+
+// listEntry is a single "<key>.<n>=value" line of a list-valued setting
+type listEntry struct {
+	index int
+	key   string
+	value string
 }
 
 // loadConfig loads and validates the configuration from a file
 func loadConfig(path string) (*Configuration, error) {
+	config, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check that other users can't tamper with what gets executed
+	if err := enforceSecurityPolicy(path, config); err != nil {
+		return nil, err
+	}
+
+	// Verify pinned checksums before anything gets executed
+	if err := verifyTargetChecksum(config.Target, config.TargetSha256); err != nil {
+		return nil, err
+	}
+	for _, route := range config.Routes {
+		if err := verifyTargetChecksum(route.Target, route.TargetSha256); err != nil {
+			return nil, fmt.Errorf("route %d: %v", route.Index, err)
+		}
+	}
+
+	// Check that the targets can actually be executed, failing early with a precise error
+	if err := preflightTarget(config.Target, config.interpreterFor(config.Target)); err != nil {
+		return nil, err
+	}
+	for _, route := range config.Routes {
+		if err := preflightTarget(route.Target, config.interpreterFor(route.Target)); err != nil {
+			return nil, fmt.Errorf("route %d: %v", route.Index, err)
+		}
+	}
+
+	return config, nil
+}
+
+// readConfig parses the configuration file and resolves its targets
+func readConfig(path string) (*Configuration, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("error opening config file: %v", err)
@@ -30,14 +142,64 @@ func loadConfig(path string) (*Configuration, error) {
 		return nil, fmt.Errorf("error parsing config file: %v", err)
 	}
 
-	// Validate target exists and is an executable file
-	if !fileExistsFunc(config.Target) {
-		return nil, fmt.Errorf("target executable not found: %s", config.Target)
+	// Resolve target candidates to the executable that exists
+	if config.Target, err = resolveTarget(config.Target); err != nil {
+		return nil, err
+	}
+	for i := range config.Routes {
+		route := &config.Routes[i]
+		if route.Target, err = resolveTarget(route.Target); err != nil {
+			return nil, fmt.Errorf("route %d: %v", route.Index, err)
+		}
 	}
 
 	return config, nil
 }
 
+// setConfigValues rewrites the configuration file with the given keys set to new values.
+// Existing lines for those keys are replaced in place, missing keys are appended. The file
+// keeps its permissions and line endings, and is replaced as a whole.
+func setConfigValues(path string, values map[string]string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	newline := "\n"
+	if strings.Contains(string(content), "\r\n") {
+		newline = "\r\n"
+	}
+	lines := strings.Split(strings.TrimRight(string(content), "\r\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	written := make(map[string]bool)
+	for i, line := range lines {
+		key, _, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || strings.HasPrefix(key, "#") {
+			continue
+		}
+		for name, value := range values {
+			if strings.EqualFold(key, name) {
+				lines[i] = key + "=" + value
+				written[name] = true
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if !written[name] {
+			lines = append(lines, name+"="+values[name])
+		}
+	}
+
+	return writeFileAtomically(path, []byte(strings.Join(lines, newline)+newline), info.Mode().Perm())
+}
+
 // parseConfig reads and parses the configuration file
 func parseConfig(reader *os.File) (*Configuration, error) {
 	config := &Configuration{}
@@ -45,6 +207,16 @@ func parseConfig(reader *os.File) (*Configuration, error) {
 
 	// Track seen keys to detect duplicates
 	seenKeys := make(map[string]bool)
+	var err error
+
+	// List-valued settings are written as "<key>.<n>=value" and collected here by key
+	lists := make(map[string][]listEntry)
+
+	// Routing rules are written as "route.<n>.<setting>=value" and collected here by index
+	routes := make(map[int]*Route)
+
+	// Dependencies are written as "waitFor.<n>.<setting>=value" and collected here by index
+	waitFor := make(map[int]*WaitCondition)
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -71,25 +243,219 @@ func parseConfig(reader *os.File) (*Configuration, error) {
 			value = value[1 : len(value)-1]
 		}
 
+		// Collect routing rule settings
+		if index, setting, ok := splitGroupKey("route", key); ok {
+			if routes[index] == nil {
+				routes[index] = &Route{Index: index, ArgCountMin: -1, ArgCountMax: -1}
+			}
+			if err := routes[index].set(setting, key, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Collect wait condition settings
+		if index, setting, ok := splitGroupKey("waitFor", key); ok {
+			if waitFor[index] == nil {
+				waitFor[index] = &WaitCondition{Index: index}
+			}
+			if err := waitFor[index].set(setting, key, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Interpreter overrides are written as "interpreter.<extension>=command"
+		if extension, ok := strings.CutPrefix(strings.ToLower(key), "interpreter."); ok {
+			if !strings.HasPrefix(extension, ".") || len(extension) < 2 {
+				return nil, fmt.Errorf("invalid interpreter key %q, must be like 'interpreter..py'", key)
+			}
+			if config.Interpreters == nil {
+				config.Interpreters = make(map[string]string)
+			}
+			config.Interpreters[extension] = value
+			continue
+		}
+
+		// Resource limits are written as "limit.<name>=value"
+		if name, ok := strings.CutPrefix(strings.ToLower(key), "limit."); ok {
+			limit, err := parseResourceLimit(name, key, value)
+			if err != nil {
+				return nil, err
+			}
+			if config.Limits == nil {
+				config.Limits = make(map[string]ResourceLimit)
+			}
+			config.Limits[name] = limit
+			continue
+		}
+
+		// Collect list entries, they are processed in index order once all lines are read
+		if prefix, index, ok := splitIndexedKey(key); ok {
+			lists[prefix] = append(lists[prefix], listEntry{index: index, key: key, value: value})
+			continue
+		}
+
 		switch strings.ToLower(key) {
 		case "target":
 			config.Target = value
+		case "targetsha256":
+			if config.TargetSha256, err = parseSha256("targetSha256", value); err != nil {
+				return nil, err
+			}
+		case "securitypolicy":
+			if config.SecurityPolicy, err = parseChoice("securityPolicy", value, "refuse", "warn", "ignore"); err != nil {
+				return nil, err
+			}
 		case "extraargs":
 			config.ExtraArgs = value
 		case "extraargsorder":
-			lowerValue := strings.ToLower(value)
-			if !slices.Contains([]string{"before", "after"}, lowerValue) {
-				return nil, fmt.Errorf("invalid extraArgsOrder value %q, must be 'before' or 'after'", value)
+			if config.ExtraArgsOrder, err = parseChoice("extraArgsOrder", value, "before", "after"); err != nil {
+				return nil, err
 			}
-			config.ExtraArgsOrder = lowerValue
 		case "hidetarget":
-			lowerValue := strings.ToLower(value)
-			if slices.Contains([]string{"true", "yes", "on"}, lowerValue) {
-				config.HideTarget = true
-			} else if slices.Contains([]string{"false", "no", "off"}, lowerValue) {
-				config.HideTarget = false
-			} else {
-				return nil, fmt.Errorf("invalid hideTarget value %q, must be 'true/yes/on' or 'false/no/off'", value)
+			if config.HideTarget, err = parseBool("hideTarget", value); err != nil {
+				return nil, err
+			}
+		case "pty":
+			if config.Pty, err = parseBool("pty", value); err != nil {
+				return nil, err
+			}
+		case "detach":
+			if config.Detach, err = parseBool("detach", value); err != nil {
+				return nil, err
+			}
+		case "pidfile":
+			config.PidFile = value
+		case "passfds":
+			if config.PassFds, err = parseFdList("passFds", value); err != nil {
+				return nil, err
+			}
+		case "listen":
+			if _, _, err := parseListenAddress("listen", value); err != nil {
+				return nil, err
+			}
+			config.Listen = value
+		case "maxconnections":
+			if config.MaxConnections, err = parseIntRange("maxConnections", value, 0, math.MaxInt32); err != nil {
+				return nil, err
+			}
+		case "retryonexitcodes":
+			if config.Retry.ExitCodes, err = parseExitCodes("retryOnExitCodes", value); err != nil {
+				return nil, err
+			}
+		case "retries":
+			if config.Retry.Retries, err = parseIntRange("retries", value, 0, 1000); err != nil {
+				return nil, err
+			}
+		case "retrydelay":
+			if config.Retry.Delay, err = parseDuration("retryDelay", value); err != nil {
+				return nil, err
+			}
+		case "retrybackoff":
+			backoff, parseErr := strconv.ParseFloat(value, 64)
+			if parseErr != nil || backoff < 1 {
+				return nil, fmt.Errorf("invalid retryBackoff value %q, must be a factor of 1 or more like 2", value)
+			}
+			config.Retry.Backoff = backoff
+		case "retrymaxdelay":
+			if config.Retry.MaxDelay, err = parseDuration("retryMaxDelay", value); err != nil {
+				return nil, err
+			}
+		case "prelaunchfailure":
+			if config.PreLaunchFailure, err = parseChoice("preLaunchFailure", value, "abort", "warn", "ignore"); err != nil {
+				return nil, err
+			}
+		case "runasuser":
+			config.RunAsUser = value
+		case "runasgroup":
+			config.RunAsGroup = value
+		case "supplementarygroups":
+			config.SupplementaryGroups = []string{}
+			for _, group := range strings.Split(value, ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					config.SupplementaryGroups = append(config.SupplementaryGroups, group)
+				}
+			}
+		case "sandbox.nonetwork":
+			if config.Sandbox.NoNetwork, err = parseBool("sandbox.noNetwork", value); err != nil {
+				return nil, err
+			}
+		case "sandbox.privatetmp":
+			if config.Sandbox.PrivateTmp, err = parseBool("sandbox.privateTmp", value); err != nil {
+				return nil, err
+			}
+		case "fsallow.unsupported":
+			if config.FsAllow.Unsupported, err = parseChoice("fsAllow.unsupported", value, "fail", "warn"); err != nil {
+				return nil, err
+			}
+		case "nice":
+			nice, err := parseIntRange("nice", value, -20, 19)
+			if err != nil {
+				return nil, err
+			}
+			config.Nice = &nice
+		case "umask":
+			umask, err := parseUmask("umask", value)
+			if err != nil {
+				return nil, err
+			}
+			config.Umask = &umask
+		case "cpuaffinity":
+			if config.CPUAffinity, err = parseCPUList("cpuAffinity", value); err != nil {
+				return nil, err
+			}
+		case "ioclass":
+			if config.IOClass, err = parseChoice("ioClass", value, "realtime", "best-effort", "idle"); err != nil {
+				return nil, err
+			}
+		case "iolevel":
+			level, err := parseIntRange("ioLevel", value, 0, 7)
+			if err != nil {
+				return nil, err
+			}
+			config.IOLevel = &level
+		case "oomscoreadj":
+			score, err := parseIntRange("oomScoreAdj", value, -1000, 1000)
+			if err != nil {
+				return nil, err
+			}
+			config.OOMScoreAdj = &score
+		case "idletimeout":
+			if config.IdleTimeout, err = parseDuration("idleTimeout", value); err != nil {
+				return nil, err
+			}
+		case "maxrss":
+			if config.MaxRSS, err = parseSize("maxRSS", value); err != nil {
+				return nil, err
+			}
+		case "logfile":
+			config.LogFile = value
+		case "reportusage":
+			if config.ReportUsage, err = parseChoice("reportUsage", value, "off", "log", "stderr"); err != nil {
+				return nil, err
+			}
+		case "descendants":
+			if config.Descendants, err = parseChoice("descendants", value, "wait", "kill", "ignore"); err != nil {
+				return nil, err
+			}
+		case "stdoutlog":
+			config.StdoutLog = value
+		case "stderrlog":
+			config.StderrLog = value
+		case "combinedlog":
+			config.CombinedLog = value
+		case "outputlogmode":
+			if config.OutputLogMode, err = parseChoice("outputLogMode", value, "append", "truncate"); err != nil {
+				return nil, err
+			}
+		case "outputlogmaxsize":
+			if config.OutputLogMaxSize, err = parseSize("outputLogMaxSize", value); err != nil {
+				return nil, err
+			}
+		case "outputlogtimestamps":
+			if config.OutputLogTimestamps, err = parseBool("outputLogTimestamps", value); err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -98,6 +464,43 @@ func parseConfig(reader *os.File) (*Configuration, error) {
 		return nil, err
 	}
 
+	// Process list-valued settings
+	for _, prefix := range slices.Sorted(maps.Keys(lists)) {
+		entries := lists[prefix]
+		slices.SortFunc(entries, func(a, b listEntry) int { return a.index - b.index })
+		for _, entry := range entries {
+			switch prefix {
+			case "prelaunch", "postexit":
+				if len(parseArgs(entry.value)) == 0 {
+					return nil, fmt.Errorf("empty command in config: %s", entry.key)
+				}
+				if prefix == "prelaunch" {
+					config.PreLaunch = append(config.PreLaunch, entry.value)
+				} else {
+					config.PostExit = append(config.PostExit, entry.value)
+				}
+			case "sandbox.readonly":
+				config.Sandbox.ReadOnly = append(config.Sandbox.ReadOnly, entry.value)
+			case "fsallow.readonly":
+				config.FsAllow.ReadOnly = append(config.FsAllow.ReadOnly, entry.value)
+			case "fsallow.readwrite":
+				config.FsAllow.ReadWrite = append(config.FsAllow.ReadWrite, entry.value)
+			case "stdoutfilter":
+				filter, err := parseOutputFilter(entry.key, entry.value)
+				if err != nil {
+					return nil, err
+				}
+				config.StdoutFilters = append(config.StdoutFilters, filter)
+			case "stderrfilter":
+				filter, err := parseOutputFilter(entry.key, entry.value)
+				if err != nil {
+					return nil, err
+				}
+				config.StderrFilters = append(config.StderrFilters, filter)
+			}
+		}
+	}
+
 	// Validate configuration
 	if config.Target == "" {
 		return nil, fmt.Errorf("target executable not specified in config")
@@ -108,9 +511,128 @@ func parseConfig(reader *os.File) (*Configuration, error) {
 		return nil, fmt.Errorf("extraArgsOrder must be specified when extraArgs is set")
 	}
 
+	if config.IOLevel != nil && config.IOClass == "" {
+		return nil, fmt.Errorf("ioClass must be specified when ioLevel is set")
+	}
+
+	if err := config.checkListenSettings(); err != nil {
+		return nil, err
+	}
+	if err := config.checkDetachSettings(); err != nil {
+		return nil, err
+	}
+	if err := config.checkRetrySettings(); err != nil {
+		return nil, err
+	}
+
+	for _, index := range slices.Sorted(maps.Keys(waitFor)) {
+		condition := waitFor[index]
+		if err := condition.validate(); err != nil {
+			return nil, err
+		}
+		config.WaitFor = append(config.WaitFor, *condition)
+	}
+
+	// Routes fall back to the top-level settings for anything they don't set themselves
+	for _, index := range slices.Sorted(maps.Keys(routes)) {
+		route := routes[index]
+		if route.Target == "" {
+			route.Target = config.Target
+			route.TargetSha256 = config.TargetSha256
+		}
+		if route.ExtraArgsOrder == "" {
+			route.ExtraArgsOrder = config.ExtraArgsOrder
+		}
+		if route.ExtraArgs != "" && route.ExtraArgsOrder == "" {
+			return nil, fmt.Errorf("extraArgsOrder must be specified when extraArgs is set for route %d", index)
+		}
+		config.Routes = append(config.Routes, *route)
+	}
+
 	return config, nil
 }
 
+// splitIndexedKey splits a list key like "stdoutFilter.2" into its lowercased prefix and index
+func splitIndexedKey(key string) (string, int, bool) {
+	dot := strings.LastIndex(key, ".")
+	if dot <= 0 {
+		return "", 0, false
+	}
+	index, err := strconv.Atoi(key[dot+1:])
+	if err != nil || index < 0 {
+		return "", 0, false
+	}
+	return strings.ToLower(key[:dot]), index, true
+}
+
+// parseBool parses a boolean config value, accepting true/yes/on and false/no/off
+func parseBool(key, value string) (bool, error) {
+	lowerValue := strings.ToLower(value)
+	if slices.Contains([]string{"true", "yes", "on"}, lowerValue) {
+		return true, nil
+	} else if slices.Contains([]string{"false", "no", "off"}, lowerValue) {
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid %s value %q, must be 'true/yes/on' or 'false/no/off'", key, value)
+}
+
+// parseChoice parses a config value that must be one of the given lowercase choices
+func parseChoice(key, value string, choices ...string) (string, error) {
+	lowerValue := strings.ToLower(value)
+	if !slices.Contains(choices, lowerValue) {
+		quoted := make([]string, len(choices))
+		for i, choice := range choices {
+			quoted[i] = "'" + choice + "'"
+		}
+		last := len(quoted) - 1
+		return "", fmt.Errorf("invalid %s value %q, must be %s or %s", key, value, strings.Join(quoted[:last], ", "), quoted[last])
+	}
+	return lowerValue, nil
+}
+
+// parseSize parses a byte size config value with an optional K, M or G suffix (powers of 1024)
+func parseSize(key, value string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(value))
+	number = strings.TrimSuffix(number, "B")
+	multiplier := int64(1)
+	if number != "" {
+		switch number[len(number)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			number = number[:len(number)-1]
+		}
+	}
+
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid %s value %q, must be a non-negative size like 512K, 10M or 1G", key, value)
+	}
+	return size * multiplier, nil
+}
+
+// parseDuration parses a non-negative duration given in seconds or like 1m30s
+func parseDuration(key, value string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid %s value %q, must be a number of seconds or a duration like 1m30s", key, value)
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		// Infinity, NaN and more seconds than a duration holds don't convert
+		if nanoseconds := seconds * float64(time.Second); nanoseconds >= 0 && nanoseconds < math.MaxInt64 {
+			return time.Duration(nanoseconds), nil
+		}
+		return 0, invalid
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, invalid
+	}
+	return duration, nil
+}
+
 // createDefaultConfig creates a default configuration file with comments
 func createDefaultConfig(configPath string) error {
 	file, err := os.Create(configPath)
@@ -0,0 +1,23 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"strings"
+)
+
+// hasCredentials reports whether the target should run as another user or group
+func (c *Configuration) hasCredentials() bool {
+	return c.RunAsUser != "" || c.RunAsGroup != "" || c.SupplementaryGroups != nil
+}
+
+// setEnv returns env with the variable set to value, replacing any previous definition
+func setEnv(env []string, name, value string) []string {
+	prefix := name + "="
+	result := make([]string, 0, len(env)+1)
+	for _, entry := range env {
+		if !strings.HasPrefix(entry, prefix) {
+			result = append(result, entry)
+		}
+	}
+	return append(result, prefix+value)
+}
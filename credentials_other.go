@@ -0,0 +1,17 @@
+//go:build !unix
+// +build !unix
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// applyCredentials fails if other credentials are configured, which is only supported on Unix
+func applyCredentials(cmd *exec.Cmd, config *Configuration) error {
+	if config.hasCredentials() {
+		return fmt.Errorf("runAsUser, runAsGroup and supplementaryGroups are only supported on Unix")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// launcherIDs returns the launcher's real and effective user and group IDs, which differ when
+// it's installed setuid or setgid. It can be overridden in tests.
+var launcherIDs = func() (uid, euid, gid, egid int) {
+	return os.Getuid(), os.Geteuid(), os.Getgid(), os.Getegid()
+}
+
+// applyCredentials makes the command run as the configured user and groups, with HOME,
+// USER and LOGNAME adjusted to match. The group defaults to the user's primary group and
+// the supplementary groups to the user's group memberships.
+func applyCredentials(cmd *exec.Cmd, config *Configuration) error {
+	if !config.hasCredentials() {
+		return nil
+	}
+
+	var account *user.User
+	realUid, effectiveUid, realGid, effectiveGid := launcherIDs()
+	uid, gid := realUid, realGid
+	if config.RunAsUser != "" {
+		var err error
+		if account, err = lookupUser(config.RunAsUser); err != nil {
+			return err
+		}
+		uid, _ = strconv.Atoi(account.Uid)
+		gid, _ = strconv.Atoi(account.Gid)
+	}
+
+	if config.RunAsGroup != "" {
+		var err error
+		if gid, err = lookupGroup(config.RunAsGroup); err != nil {
+			return err
+		}
+	}
+
+	groups := []uint32{}
+	switch {
+	case config.SupplementaryGroups != nil:
+		for _, name := range config.SupplementaryGroups {
+			id, err := lookupGroup(name)
+			if err != nil {
+				return err
+			}
+			groups = append(groups, uint32(id))
+		}
+	case account != nil:
+		ids, err := account.GroupIds()
+		if err != nil {
+			return fmt.Errorf("failed to look up groups of user %s: %v", account.Username, err)
+		}
+		for _, id := range ids {
+			if n, err := strconv.Atoi(id); err == nil {
+				groups = append(groups, uint32(n))
+			}
+		}
+	}
+
+	// Nothing to switch if the target would run as the launcher does anyway, and only root
+	// can switch to other credentials. A setuid or setgid launcher always switches, as the
+	// target would otherwise inherit its effective IDs.
+	unchanged := realUid == effectiveUid && realGid == effectiveGid &&
+		uid == effectiveUid && gid == effectiveGid && hasCurrentGroups(groups, gid)
+	if !unchanged {
+		if effectiveUid != 0 {
+			return fmt.Errorf("running the target as another user or group requires root privileges")
+		}
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Credential = &syscall.Credential{
+			Uid:    uint32(uid),
+			Gid:    uint32(gid),
+			Groups: groups,
+		}
+	}
+
+	if account != nil {
+		env := cmd.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		env = setEnv(env, "HOME", account.HomeDir)
+		env = setEnv(env, "USER", account.Username)
+		env = setEnv(env, "LOGNAME", account.Username)
+		cmd.Env = env
+	}
+	return nil
+}
+
+// hasCurrentGroups reports whether the groups are the launcher's supplementary groups, in any
+// order. The primary group counts as a member of both, as it grants its access either way.
+func hasCurrentGroups(groups []uint32, gid int) bool {
+	current, err := os.Getgroups()
+	if err != nil {
+		return false
+	}
+	wanted := map[int]bool{gid: true}
+	for _, group := range groups {
+		wanted[int(group)] = true
+	}
+	have := map[int]bool{gid: true}
+	for _, group := range current {
+		if !wanted[group] {
+			return false
+		}
+		have[group] = true
+	}
+	return len(have) == len(wanted)
+}
+
+// lookupUser finds a user by name or numeric ID
+func lookupUser(name string) (*user.User, error) {
+	account, err := user.Lookup(name)
+	if err != nil {
+		if _, isNumeric := strconv.Atoi(name); isNumeric == nil {
+			account, err = user.LookupId(name)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("runAsUser %s not found: %v", name, err)
+	}
+	return account, nil
+}
+
+// lookupGroup finds a group ID by name or numeric ID
+func lookupGroup(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	group, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("group %s not found: %v", name, err)
+	}
+	return strconv.Atoi(group.Gid)
+}
@@ -0,0 +1,68 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// TestRunAsCurrentUser tests that running as the current user and groups needs no privileges
+// and leaves the credentials alone
+func TestRunAsCurrentUser(t *testing.T) {
+	currentGroups := []string{}
+	groups, _ := os.Getgroups()
+	for _, group := range groups {
+		currentGroups = append(currentGroups, strconv.Itoa(group))
+	}
+	slices.Reverse(currentGroups)
+
+	cmd := exec.Command("sh")
+	config := &Configuration{
+		RunAsUser:           strconv.Itoa(os.Getuid()),
+		RunAsGroup:          strconv.Itoa(os.Getgid()),
+		SupplementaryGroups: append(currentGroups, currentGroups...),
+	}
+	if err := applyCredentials(cmd, config); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cmd.SysProcAttr != nil && cmd.SysProcAttr.Credential != nil {
+		t.Errorf("Expected credentials to be left alone, got %+v", cmd.SysProcAttr.Credential)
+	}
+}
+
+// TestRunAsInvokingUserSetuid tests that a setuid-root launcher drops its privileges when
+// running the target as the user who invoked it
+func TestRunAsInvokingUserSetuid(t *testing.T) {
+	originalLauncherIDs := launcherIDs
+	defer func() { launcherIDs = originalLauncherIDs }()
+	launcherIDs = func() (uid, euid, gid, egid int) {
+		return os.Getuid(), 0, os.Getgid(), os.Getgid()
+	}
+	if os.Getuid() == 0 {
+		// Run by root, the launcher pretends to be invoked by nobody instead
+		nobody, err := user.Lookup("nobody")
+		if err != nil {
+			t.Skipf("User nobody unknown: %v", err)
+		}
+		nobodyUid, _ := strconv.Atoi(nobody.Uid)
+		nobodyGid, _ := strconv.Atoi(nobody.Gid)
+		launcherIDs = func() (uid, euid, gid, egid int) { return nobodyUid, 0, nobodyGid, nobodyGid }
+	}
+	uid, _, gid, _ := launcherIDs()
+
+	cmd := exec.Command("sh")
+	config := &Configuration{RunAsUser: strconv.Itoa(uid), RunAsGroup: strconv.Itoa(gid), SupplementaryGroups: []string{}}
+	if err := applyCredentials(cmd, config); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cmd.SysProcAttr == nil || cmd.SysProcAttr.Credential == nil ||
+		cmd.SysProcAttr.Credential.Uid != uint32(uid) || cmd.SysProcAttr.Credential.Gid != uint32(gid) {
+		t.Errorf("Expected credentials of UID %d and GID %d to be set, got %+v", uid, gid, cmd.SysProcAttr)
+	}
+}
@@ -0,0 +1,196 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// checkDetachSettings rejects settings that need the launcher to stay around while the target
+// runs, which a detached target can't have
+func (c *Configuration) checkDetachSettings() error {
+	if !c.Detach {
+		if c.PidFile != "" {
+			return fmt.Errorf("pidFile requires detach")
+		}
+		return nil
+	}
+
+	conflicts := []struct {
+		key string
+		set bool
+	}{
+		{"pty", c.Pty},
+		{"postExit", len(c.PostExit) > 0},
+		{"stdoutFilter", len(c.StdoutFilters) > 0},
+		{"stderrFilter", len(c.StderrFilters) > 0},
+		{"outputLogTimestamps", c.OutputLogTimestamps},
+		{"outputLogMaxSize", c.OutputLogMaxSize > 0},
+		{"idleTimeout", c.IdleTimeout > 0},
+		{"maxRSS", c.MaxRSS > 0},
+		{"descendants", c.Descendants == "wait" || c.Descendants == "kill"},
+		{"reportUsage", c.ReportUsage == "log" || c.ReportUsage == "stderr"},
+	}
+	for _, conflict := range conflicts {
+		if conflict.set {
+			return fmt.Errorf("%s can't be used with detach", conflict.key)
+		}
+	}
+	if c.CombinedLog != "" && (c.StdoutLog != "" || c.StderrLog != "") {
+		return fmt.Errorf("combinedLog can't be used together with stdoutLog or stderrLog with detach")
+	}
+	return nil
+}
+
+// openDetachedOutput connects the command's stdin to the null device and its stdout and
+// stderr directly to the configured log files, or the null device for streams without one.
+// The returned function closes the launcher's copies of the files once the command started.
+func openDetachedOutput(cmd *exec.Cmd, config *Configuration) (func(), error) {
+	var files []*os.File
+	closeFiles := func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}
+	open := func(path string, flag int) (*os.File, error) {
+		file, err := os.OpenFile(path, flag, 0644)
+		if err != nil {
+			closeFiles()
+			return nil, err
+		}
+		files = append(files, file)
+		return file, nil
+	}
+
+	logFlag := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if config.OutputLogMode == "truncate" {
+		logFlag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	stdoutPath, stderrPath := config.StdoutLog, config.StderrLog
+	if config.CombinedLog != "" {
+		stdoutPath, stderrPath = config.CombinedLog, config.CombinedLog
+	}
+
+	null, err := open(os.DevNull, os.O_RDWR)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %v", os.DevNull, err)
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = null, null, null
+
+	if stdoutPath != "" {
+		if cmd.Stdout, err = open(stdoutPath, logFlag); err != nil {
+			return nil, fmt.Errorf("error opening output log: %v", err)
+		}
+	}
+	if stderrPath == stdoutPath && stderrPath != "" {
+		cmd.Stderr = cmd.Stdout
+	} else if stderrPath != "" {
+		if cmd.Stderr, err = open(stderrPath, logFlag); err != nil {
+			return nil, fmt.Errorf("error opening output log: %v", err)
+		}
+	}
+	return closeFiles, nil
+}
+
+// pidFile is a PID file claimed by this launcher. It stays locked until closed, so launches
+// at the same time don't both find it stale and start the target twice.
+type pidFile struct {
+	file    *os.File
+	path    string
+	written bool
+}
+
+// claimPidFile creates or locks the PID file, and fails if it names a running target or another
+// launch holds it. A stale file from a target that has exited is taken over.
+func claimPidFile(path string) (*pidFile, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening PID file: %v", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("target is being launched by another process holding %s", path)
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading PID file: %v", err)
+	}
+	if pid, running := pidFileRunning(string(content)); running {
+		file.Close()
+		return nil, fmt.Errorf("target is already running with PID %d according to %s", pid, path)
+	}
+	if len(content) > 0 {
+		logf("replacing stale PID file %s", path)
+	}
+	return &pidFile{file: file, path: path}, nil
+}
+
+// pidFileRunning returns the PID named by the PID file content, and whether that process still
+// runs. The start time on the second line tells it apart from a process that reused the PID.
+func pidFileRunning(content string) (int, bool) {
+	pidLine, startLine, _ := strings.Cut(content, "\n")
+	pid, err := strconv.Atoi(strings.TrimSpace(pidLine))
+	if err != nil || pid <= 0 || !processAlive(pid) {
+		return pid, false
+	}
+	start := strings.TrimSpace(startLine)
+	return pid, start == "" || processStartTime(pid) == start
+}
+
+// write stores the target's PID, followed by its start time where the platform tells it
+func (p *pidFile) write(pid int) error {
+	content := fmt.Sprintf("%d\n", pid)
+	if start := processStartTime(pid); start != "" {
+		content += start + "\n"
+	}
+	err := p.file.Truncate(0)
+	if err == nil {
+		_, err = p.file.WriteAt([]byte(content), 0)
+	}
+	if err == nil {
+		err = p.file.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("error writing PID file: %v", err)
+	}
+	p.written = true
+	return nil
+}
+
+// close releases the PID file, removing it if no target was started
+func (p *pidFile) close() {
+	if !p.written {
+		os.Remove(p.path)
+	}
+	p.file.Close()
+}
+
+// writeFileAtomically replaces the file's content through a temporary file with the given
+// permissions, so readers never see a partially written file
+func writeFileAtomically(path string, data []byte, perm os.FileMode) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
@@ -0,0 +1,24 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// firstExtraFd is the first file descriptor after stdin, stdout and stderr
+const firstExtraFd = 3
+
+// parseFdList parses a comma-separated list of file descriptor numbers beyond stdio
+func parseFdList(key, value string) ([]int, error) {
+	var fds []int
+	for _, part := range strings.Split(value, ",") {
+		fd, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || fd < firstExtraFd {
+			return nil, fmt.Errorf("invalid %s value %q, must be file descriptor numbers of %d or more like '3,5'", key, value, firstExtraFd)
+		}
+		fds = append(fds, fd)
+	}
+	return fds, nil
+}
@@ -0,0 +1,77 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestPassInheritedFds tests that socket activation sockets and configured descriptors reach
+// the target at the same numbers, with LISTEN_PID set to the target's PID. The test binary
+// re-runs this test as the socket-activated launcher.
+func TestPassInheritedFds(t *testing.T) {
+	if os.Getenv("PROXYLAUNCHER_TEST_ACTIVATION") != "" {
+		execCommand = func(command string, args ...string) *exec.Cmd {
+			return exec.Command("sh", "-c", `echo "$LISTEN_PID $$ $LISTEN_FDS $LISTEN_FDNAMES"; readlink /proc/$$/fd/3; echo via fd 5 >&5`)
+		}
+		if err := NewLauncher(&Configuration{Target: "sh", PassFds: []int{5}}).Launch(); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		// A descriptor the launcher opened itself isn't passed on
+		file, err := os.Open(os.Args[0])
+		if err != nil {
+			t.Fatalf("Failed to open file: %v", err)
+		}
+		defer file.Close()
+		err = NewLauncher(&Configuration{Target: "sh", PassFds: []int{int(file.Fd())}}).Launch()
+		if err == nil || !strings.Contains(err.Error(), "wasn't passed to the launcher") {
+			t.Errorf("Expected error for descriptor %d of the launcher, got: %v", file.Fd(), err)
+		}
+		return
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	socket, err := listener.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Failed to get socket file: %v", err)
+	}
+	defer socket.Close()
+	pipeReader, pipeWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer pipeReader.Close()
+
+	// The shell sets LISTEN_PID to its PID, which the test binary keeps when executed
+	cmd := exec.Command("sh", "-c", `LISTEN_PID=$$ exec "$0" -test.run=^TestPassInheritedFds$`, os.Args[0])
+	cmd.Env = append(os.Environ(), "PROXYLAUNCHER_TEST_ACTIVATION=1", "LISTEN_FDS=1", "LISTEN_FDNAMES=http")
+	cmd.ExtraFiles = []*os.File{socket, nil, pipeWriter}
+	output, err := cmd.Output()
+	pipeWriter.Close()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v\n%s", err, output)
+	}
+
+	lines := strings.Split(string(output), "\n")
+	fields := strings.Fields(lines[0])
+	if len(fields) != 4 || fields[0] != fields[1] || fields[2] != "1" || fields[3] != "http" {
+		t.Errorf("Expected LISTEN_PID of the target, LISTEN_FDS=1 and the names, got %q", lines[0])
+	}
+	if len(lines) < 2 || !strings.HasPrefix(lines[1], "socket:") {
+		t.Errorf("Expected socket at fd 3, got %q", output)
+	}
+	if passed, _ := io.ReadAll(pipeReader); string(passed) != "via fd 5\n" {
+		t.Errorf("Expected output through fd 5, got %q", passed)
+	}
+}
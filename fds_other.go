@@ -0,0 +1,20 @@
+//go:build !unix
+// +build !unix
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// passInheritedFds fails if descriptors to pass are configured, which is only supported on Unix
+func passInheritedFds(cmd *exec.Cmd, config *Configuration) (bool, error) {
+	if len(config.PassFds) > 0 {
+		return false, fmt.Errorf("passFds is only supported on Unix")
+	}
+	return false, nil
+}
+
+// recordInheritedFds has nothing to record, as descriptors aren't passed on this platform
+func recordInheritedFds() {}
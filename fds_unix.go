@@ -0,0 +1,142 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// inheritedFiles keeps the inherited descriptors wrapped once, as a collected *os.File would close its descriptor
+var (
+	inheritedFiles   = make(map[int]*os.File)
+	inheritedFilesMu sync.Mutex
+)
+
+// inheritedFds are the descriptors beyond stdio the launcher was started with. They have to be
+// recorded before it opens any of its own, which could otherwise take numbers that weren't passed.
+var inheritedFds map[int]bool
+
+// maxScannedFd limits the search for inherited descriptors where they can't be listed
+const maxScannedFd = 1024
+
+// recordInheritedFds records the descriptors the launcher inherited. Call it first thing.
+func recordInheritedFds() {
+	inheritedFds = make(map[int]bool)
+	for _, fd := range openFds() {
+		if fd < firstExtraFd {
+			continue
+		}
+		// Descriptors that survived executing the launcher can't be close-on-exec, unlike all
+		// the Go runtime opens itself
+		if flags, err := unix.FcntlInt(uintptr(fd), unix.F_GETFD, 0); err == nil && flags&unix.FD_CLOEXEC == 0 {
+			inheritedFds[fd] = true
+		}
+	}
+}
+
+// openFds lists the process's open descriptors, or the possible ones up to maxScannedFd if
+// /dev/fd can't be read
+func openFds() []int {
+	var fds []int
+	dir, err := unix.Open("/dev/fd", unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		for fd := range maxScannedFd {
+			fds = append(fds, fd)
+		}
+		return fds
+	}
+	defer unix.Close(dir)
+
+	buffer := make([]byte, 4096)
+	var names []string
+	for {
+		n, err := unix.ReadDirent(dir, buffer)
+		if err != nil || n <= 0 {
+			break
+		}
+		_, _, names = unix.ParseDirent(buffer[:n], -1, names)
+	}
+	for _, name := range names {
+		if fd, err := strconv.Atoi(name); err == nil && fd != dir {
+			fds = append(fds, fd)
+		}
+	}
+	return fds
+}
+
+// passInheritedFds passes file descriptors the launcher inherited on to the target, at the
+// same numbers: the sockets of a systemd socket activation and the ones listed in passFds.
+// It reports whether sockets of a socket activation are passed, in which case LISTEN_PID
+// has to be set to the target's PID once it's known.
+func passInheritedFds(cmd *exec.Cmd, config *Configuration) (bool, error) {
+	fds := slices.Clone(config.PassFds)
+
+	activationFds := socketActivationFds()
+	for fd := firstExtraFd; fd < firstExtraFd+activationFds; fd++ {
+		if !slices.Contains(fds, fd) {
+			fds = append(fds, fd)
+		}
+	}
+	if len(fds) == 0 {
+		return false, nil
+	}
+
+	cmd.ExtraFiles = make([]*os.File, slices.Max(fds)-firstExtraFd+1)
+	for _, fd := range fds {
+		file, err := inheritedFile(fd)
+		if err != nil {
+			return false, err
+		}
+		cmd.ExtraFiles[fd-firstExtraFd] = file
+	}
+
+	if activationFds > 0 {
+		// LISTEN_FDS and LISTEN_FDNAMES stay as they are, LISTEN_PID is set for the target later
+		env := cmd.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		cmd.Env = slices.DeleteFunc(slices.Clone(env), func(entry string) bool { return strings.HasPrefix(entry, "LISTEN_PID=") })
+	}
+	return activationFds > 0, nil
+}
+
+// socketActivationFds returns the number of sockets systemd passed to the launcher, 0 if it
+// wasn't socket-activated. Only Linux is considered, as setting LISTEN_PID for the target
+// needs the sandbox init there.
+func socketActivationFds() int {
+	if runtime.GOOS != "linux" || os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 0 {
+		return 0
+	}
+	return count
+}
+
+// inheritedFile returns the open inherited descriptor as a file
+func inheritedFile(fd int) (*os.File, error) {
+	inheritedFilesMu.Lock()
+	defer inheritedFilesMu.Unlock()
+
+	if file, ok := inheritedFiles[fd]; ok {
+		return file, nil
+	}
+	if !inheritedFds[fd] {
+		return nil, fmt.Errorf("file descriptor %d to pass to the target wasn't passed to the launcher", fd)
+	}
+	file := os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+	inheritedFiles[fd] = file
+	return file, nil
+}
@@ -0,0 +1,115 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// maxFilterLineLength bounds how much of an unterminated line is held back for
+// filtering; longer lines are filtered in pieces rather than buffered without limit
+const maxFilterLineLength = 64 * 1024
+
+// outputFilter is a single rule applied to each line of the target's output
+type outputFilter struct {
+	Pattern     *regexp.Regexp
+	Drop        bool
+	Replacement string
+}
+
+// parseOutputFilter parses a filter rule of the form "drop <regex>" or "replace <regex> => <replacement>"
+func parseOutputFilter(key, value string) (outputFilter, error) {
+	action, rule, _ := strings.Cut(value, " ")
+	rule = strings.TrimSpace(rule)
+
+	var filter outputFilter
+	var pattern string
+	switch strings.ToLower(action) {
+	case "drop":
+		filter.Drop = true
+		pattern = rule
+	case "replace":
+		var found bool
+		pattern, filter.Replacement, found = strings.Cut(rule, " => ")
+		if !found {
+			return outputFilter{}, fmt.Errorf("invalid %s value %q, replace rules must be 'replace <regex> => <replacement>'", key, value)
+		}
+	default:
+		return outputFilter{}, fmt.Errorf("invalid %s value %q, must start with 'drop' or 'replace'", key, value)
+	}
+
+	if pattern == "" {
+		return outputFilter{}, fmt.Errorf("invalid %s value %q, regular expression is empty", key, value)
+	}
+	var err error
+	if filter.Pattern, err = regexp.Compile(pattern); err != nil {
+		return outputFilter{}, fmt.Errorf("invalid %s regular expression: %v", key, err)
+	}
+	return filter, nil
+}
+
+// lineFilterWriter applies output filters line by line before passing output on.
+// Only the current unterminated line is buffered; call Flush once the stream ends.
+type lineFilterWriter struct {
+	out     io.Writer
+	filters []outputFilter
+	pending []byte
+}
+
+func (l *lineFilterWriter) Write(p []byte) (int, error) {
+	l.pending = append(l.pending, p...)
+	for {
+		end := bytes.IndexByte(l.pending, '\n')
+		if end < 0 {
+			if len(l.pending) < maxFilterLineLength {
+				break
+			}
+			end = len(l.pending) - 1
+		}
+		if err := l.writeLine(l.pending[:end+1]); err != nil {
+			return 0, err
+		}
+		l.pending = l.pending[end+1:]
+	}
+
+	// Compact the buffer so it doesn't keep growing behind the slice start
+	l.pending = append([]byte(nil), l.pending...)
+	return len(p), nil
+}
+
+// Flush filters and writes any remaining unterminated line
+func (l *lineFilterWriter) Flush() error {
+	if len(l.pending) == 0 {
+		return nil
+	}
+	err := l.writeLine(l.pending)
+	l.pending = nil
+	return err
+}
+
+// writeLine applies the filters to a single line, including its line ending if any
+func (l *lineFilterWriter) writeLine(line []byte) error {
+	content, ending := splitLineEnding(line)
+	for _, filter := range l.filters {
+		if !filter.Pattern.Match(content) {
+			continue
+		}
+		if filter.Drop {
+			return nil
+		}
+		content = filter.Pattern.ReplaceAll(content, []byte(filter.Replacement))
+	}
+
+	_, err := l.out.Write(append(content, ending...))
+	return err
+}
+
+// splitLineEnding separates a trailing "\n" or "\r\n" from a line
+func splitLineEnding(line []byte) ([]byte, []byte) {
+	content := bytes.TrimSuffix(line, []byte("\n"))
+	content = bytes.TrimSuffix(content, []byte("\r"))
+	return content[:len(content):len(content)], line[len(content):]
+}
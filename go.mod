@@ -6,7 +6,7 @@ toolchain go1.23.2
 
 require (
 	github.com/ncruces/zenity v0.10.14
-	golang.org/x/sys v0.32.0 // indirect
+	golang.org/x/sys v0.32.0
 )
 
 require (
@@ -0,0 +1,74 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// exitCodeEnvVar passes the target's exit code to post-exit hooks
+const exitCodeEnvVar = "PROXYLAUNCHER_EXIT_CODE"
+
+// runPreLaunchHooks runs the preLaunch commands in order, handling failures per preLaunchFailure
+func (l *Launcher) runPreLaunchHooks() error {
+	for _, hook := range l.Config.PreLaunch {
+		err := runHook(hook, nil)
+		if err == nil {
+			continue
+		}
+
+		switch l.Config.PreLaunchFailure {
+		case "ignore":
+		case "warn":
+			logf("pre-launch hook %q failed: %v", hook, err)
+		default: // abort
+			return fmt.Errorf("pre-launch hook %q failed: %v", hook, err)
+		}
+	}
+	return nil
+}
+
+// runPostExitHooks runs the postExit commands in order, passing the target's exit code.
+// Failures are logged but don't stop the remaining hooks.
+func (l *Launcher) runPostExitHooks(exitCode int) {
+	env := []string{exitCodeEnvVar + "=" + strconv.Itoa(exitCode)}
+	for _, hook := range l.Config.PostExit {
+		if err := runHook(hook, env); err != nil {
+			logf("post-exit hook %q failed: %v", hook, err)
+		}
+	}
+}
+
+// runHook runs a single hook command line, split like extraArgs, with extra environment variables.
+// Hooks get no stdin and write to stderr so they don't interfere with the target's streams.
+func runHook(command string, env []string) error {
+	args := parseArgs(command)
+	cmd := execCommand(args[0], args[1:]...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd.Run()
+}
+
+// exitCodeOf returns the exit code reported by a target run's error: 0 on success, the
+// watchdog's exit code if one killed the target, the process's exit code if it ran, or -1
+// if it couldn't be run or was killed by a signal
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	var killedErr *watchdogError
+	if errors.As(err, &killedErr) {
+		return killedErr.exitCode
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
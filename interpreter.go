@@ -0,0 +1,66 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// defaultInterpreters returns the built-in interpreter command lines by lowercase file extension
+func defaultInterpreters() map[string]string {
+	interpreters := map[string]string{
+		".py":  "python3",
+		".js":  "node",
+		".jar": "java -jar",
+		".ps1": "pwsh -NoProfile -File",
+	}
+	if runtime.GOOS == "windows" {
+		interpreters[".py"] = "python"
+		interpreters[".ps1"] = "powershell -NoProfile -ExecutionPolicy Bypass -File"
+	}
+	return interpreters
+}
+
+// interpreterFor returns the interpreter command line (program and arguments) used to run
+// the target, or nil if the target is executed directly. Interpreters configured as
+// "interpreter.<extension>" take precedence over the built-in ones; an empty one disables
+// the built-in interpreter for that extension. The built-in ones are only used for targets
+// that can't be executed directly, so a script's own "#!" line keeps choosing its interpreter.
+func (c *Configuration) interpreterFor(target string) []string {
+	extension := strings.ToLower(filepath.Ext(target))
+	if extension == "" {
+		return nil
+	}
+
+	interpreter, configured := c.Interpreters[extension]
+	if !configured {
+		if isExecutableScript(target) {
+			return nil
+		}
+		interpreter = defaultInterpreters()[extension]
+	}
+	if args := parseArgs(interpreter); len(args) > 0 {
+		return args
+	}
+	return nil
+}
+
+// isExecutableScript reports whether the target is an executable script with a "#!" line,
+// which the system runs through the interpreter named there. Windows ignores such lines.
+func isExecutableScript(target string) bool {
+	if runtime.GOOS == "windows" || checkExecPermission(target) != nil {
+		return false
+	}
+	file, err := os.Open(target)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header := make([]byte, 2)
+	n, _ := io.ReadFull(file, header)
+	return string(header[:n]) == "#!"
+}
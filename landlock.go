@@ -0,0 +1,26 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import "fmt"
+
+// FsAllowConfig lists the only paths the target may access, enforced with Landlock
+type FsAllowConfig struct {
+	ReadOnly    []string // paths the target may read and execute below
+	ReadWrite   []string // paths the target may fully access below
+	Unsupported string   // "fail" (default) or "warn" if Landlock can't enforce the restrictions fully
+}
+
+// enabled reports whether filesystem restrictions are configured
+func (f *FsAllowConfig) enabled() bool {
+	return len(f.ReadOnly) > 0 || len(f.ReadWrite) > 0
+}
+
+// unsupported applies the configured policy to a restriction that can't be enforced,
+// returning an error unless the policy is to only warn about it
+func (f *FsAllowConfig) unsupported(problem string) error {
+	if f.Unsupported == "warn" {
+		logf("warning: %s", problem)
+		return nil
+	}
+	return fmt.Errorf("filesystem restrictions can't be enforced: %s", problem)
+}
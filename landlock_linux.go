@@ -0,0 +1,149 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// landlockFullABI is the first Landlock ABI version restricting all filesystem changes,
+// earlier versions can't prevent truncating files
+const landlockFullABI = 3
+
+// landlockFileAccess are the access rights that apply to files, as opposed to directories
+const landlockFileAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+	unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+
+// landlockReadAccess are the access rights granted below read-only paths
+const landlockReadAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+
+// landlockRuleset is the Landlock policy the sandbox init applies before executing the target
+type landlockRuleset struct {
+	HandledAccess uint64 // access rights restricted at all, depending on the ABI version
+	ReadOnly      []string
+	ReadWrite     []string
+}
+
+// landlockABI returns the Landlock ABI version supported by the kernel, 0 if Landlock is unavailable
+func landlockABI() int {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0
+	}
+	return int(abi)
+}
+
+// landlockHandledAccess returns the filesystem access rights a Landlock ABI version can restrict
+func landlockHandledAccess(abi int) uint64 {
+	// ABI 1 can't grant moving files between directories, so it denies that unconditionally
+	access := uint64(unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR | unix.LANDLOCK_ACCESS_FS_REMOVE_DIR | unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR | unix.LANDLOCK_ACCESS_FS_MAKE_DIR | unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK | unix.LANDLOCK_ACCESS_FS_MAKE_FIFO | unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM)
+	if abi >= 2 {
+		access |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		access |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		access |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+	return access
+}
+
+// newLandlockRuleset builds the Landlock policy for the command from the fsAllow settings.
+// Besides the configured paths, the target may execute its own executable and fully access
+// its working directory. It returns nil if Landlock is unavailable and that's only warned about.
+func newLandlockRuleset(cmd *exec.Cmd, config *Configuration) (*landlockRuleset, error) {
+	abi := landlockABI()
+	if abi == 0 {
+		return nil, config.FsAllow.unsupported("the kernel doesn't support Landlock, running the target without filesystem restrictions")
+	}
+	if abi < landlockFullABI {
+		if err := config.FsAllow.unsupported(fmt.Sprintf("the kernel only supports Landlock ABI %d, truncating files isn't restricted", abi)); err != nil {
+			return nil, err
+		}
+	}
+
+	workDir := cmd.Dir
+	if workDir == "" {
+		var err error
+		if workDir, err = os.Getwd(); err != nil {
+			return nil, fmt.Errorf("failed to determine working directory: %v", err)
+		}
+	}
+
+	return &landlockRuleset{
+		HandledAccess: landlockHandledAccess(abi),
+		ReadOnly:      append([]string{cmd.Path}, config.FsAllow.ReadOnly...),
+		ReadWrite:     append([]string{workDir}, config.FsAllow.ReadWrite...),
+	}, nil
+}
+
+// restrictSelf applies the policy to the calling thread, which must then execute the target.
+// Paths that don't exist are skipped, as there's nothing to grant access to.
+func (r *landlockRuleset) restrictSelf() error {
+	attr := unix.LandlockRulesetAttr{Access_fs: r.HandledAccess}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("creating Landlock ruleset: %v", errno)
+	}
+	defer unix.Close(int(fd))
+
+	for _, path := range r.ReadOnly {
+		if err := addLandlockRule(int(fd), path, landlockReadAccess&r.HandledAccess); err != nil {
+			return err
+		}
+	}
+	for _, path := range r.ReadWrite {
+		if err := addLandlockRule(int(fd), path, r.HandledAccess); err != nil {
+			return err
+		}
+	}
+
+	// Required to restrict an unprivileged process, and keeps setuid executables from escaping
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("setting no_new_privs: %v", err)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("enforcing Landlock ruleset: %v", errno)
+	}
+	return nil
+}
+
+// addLandlockRule allows the access rights below a path, limited to file rights if it isn't a directory
+func addLandlockRule(rulesetFd int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("opening fsAllow path %s: %v", path, err)
+	}
+	defer unix.Close(fd)
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("opening fsAllow path %s: %v", path, err)
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= landlockFileAccess
+	}
+
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFd), unix.LANDLOCK_RULE_PATH_BENEATH,
+		uintptr(unsafe.Pointer(&rule)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("allowing access to %s: %v", path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFsAllow tests that the target can only access its working directory and the allowed paths
+func TestFsAllow(t *testing.T) {
+	if abi := landlockABI(); abi < landlockFullABI {
+		t.Skipf("Landlock ABI %d not supported, kernel has %d", landlockFullABI, abi)
+	}
+
+	tempDir := t.TempDir()
+	workDir := filepath.Join(tempDir, "work")
+	readOnlyDir := filepath.Join(tempDir, "readonly")
+	writableDir := filepath.Join(tempDir, "writable")
+	hiddenDir := filepath.Join(tempDir, "hidden")
+	for _, dir := range []string{workDir, readOnlyDir, writableDir, hiddenDir} {
+		os.Mkdir(dir, 0755)
+	}
+	os.WriteFile(filepath.Join(readOnlyDir, "input"), []byte("input data\n"), 0644)
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := exec.Command("sh", "-c", `
+cat `+readOnlyDir+`/input
+touch `+readOnlyDir+`/file 2>/dev/null || echo readonly denied
+touch `+writableDir+`/file && echo writable allowed
+ls `+hiddenDir+` 2>/dev/null || echo hidden denied
+touch file && echo workdir allowed`)
+		cmd.Dir = workDir
+		return cmd
+	}
+
+	logPath := filepath.Join(workDir, "stdout.log")
+	launcher := NewLauncher(&Configuration{
+		Target: "sh",
+		FsAllow: FsAllowConfig{
+			ReadOnly:  []string{"/bin", "/usr", "/lib", "/lib64", "/etc", readOnlyDir},
+			ReadWrite: []string{"/dev/null", writableDir},
+		},
+		StdoutLog:     logPath,
+		OutputLogMode: "truncate",
+	})
+	if err := launcher.Launch(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content, _ := os.ReadFile(logPath)
+	expected := []string{"input data", "readonly denied", "writable allowed", "hidden denied", "workdir allowed"}
+	if lines := strings.Split(strings.TrimSpace(string(content)), "\n"); strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected output %q, got %q", expected, lines)
+	}
+}
@@ -2,67 +2,311 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 )
 
 // Launcher handles launching target applications
 type Launcher struct {
-	Config    *Configuration
-	DebugMode bool
+	Config     *Configuration
+	ConfigPath string   // where Config was loaded from, for status records
+	Profile    string   // name of this launcher, for status records
+	Args       []string // received command line arguments, without this executable's path
+	Env        []string // additional environment variables for the target
+	DebugMode  bool
+
+	pidFile *pidFile // claimed while launching a detached target
+
+	// The target's standard streams
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
 }
 
 // NewLauncher creates a new launcher instance
 func NewLauncher(config *Configuration) *Launcher {
 	return &Launcher{
 		Config:    config,
+		Profile:   launcherProfile(),
+		Args:      os.Args[1:],
 		DebugMode: os.Getenv("PROXYLAUNCHER_DEBUG") == "true", // Keep for testing only
+		Stdin:     os.Stdin,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
 	}
 }
 
-// Launch starts the target application with configured settings
+// processStartMu serializes starting targets, as the umask set around it is process-wide
+var processStartMu sync.Mutex
+
+// Launch starts the target application with configured settings once its dependencies are
+// ready, surrounded by the configured pre-launch and post-exit hooks
 func (l *Launcher) Launch() error {
+	// Don't start a second instance while a detached target is still running, or while
+	// another launch is under way
+	if l.Config.PidFile != "" {
+		pidFile, err := claimPidFile(l.Config.PidFile)
+		if err != nil {
+			return err
+		}
+		l.pidFile = pidFile
+		defer func() {
+			pidFile.close()
+			l.pidFile = nil
+		}()
+	}
+
+	// Wait for the dependencies first, the hooks may need them as well
+	if len(l.Config.WaitFor) > 0 {
+		if err := waitForConditions(l.Config.WaitFor); err != nil {
+			return err
+		}
+	}
+
+	if err := l.runPreLaunchHooks(); err != nil {
+		return err
+	}
+
+	err := l.runTargetWithRetries()
+
+	l.runPostExitHooks(exitCodeOf(err))
+	return err
+}
+
+// runTarget runs the target application and waits for it to exit
+func (l *Launcher) runTarget() error {
+	receivedArgs := l.Args
+
+	// Pick target and extra arguments, from the first matching route if any
+	target, extraArgs, extraArgsOrder := l.Config.Target, l.Config.ExtraArgs, l.Config.ExtraArgsOrder
+	if route := l.Config.selectRoute(receivedArgs); route != nil {
+		target, extraArgs, extraArgsOrder = route.Target, route.ExtraArgs, route.ExtraArgsOrder
+	}
+
 	// Parse extraArgs if present
 	args := []string{}
-	if l.Config.ExtraArgs != "" {
-		args = parseArgs(l.Config.ExtraArgs)
+	if extraArgs != "" {
+		args = parseArgs(extraArgs)
 	}
 
-	// Get received command line args (skip the first argument which is this executable's path)
-	receivedArgs := os.Args[1:]
-
 	// Combine arguments based on extraArgsOrder
 	var allArgs []string
-	if strings.ToLower(l.Config.ExtraArgsOrder) == "before" {
+	if strings.ToLower(extraArgsOrder) == "before" {
 		allArgs = append(args, receivedArgs...)
 	} else {
-		allArgs = append(receivedArgs, args...)
+		allArgs = append(slices.Clone(receivedArgs), args...)
+	}
+
+	// Run scripts through their interpreter: interpreter, its arguments, the script, then all other arguments
+	if interpreter := l.Config.interpreterFor(target); interpreter != nil {
+		allArgs = append(append(interpreter[1:], target), allArgs...)
+		target = interpreter[0]
 	}
 
 	// Prepare the command using our mockable execCommand
-	cmd := execCommand(l.Config.Target, allArgs...)
+	cmd := execCommand(target, allArgs...)
+	if len(l.Env) > 0 {
+		cmd.Env = append(cmd.Environ(), l.Env...)
+	}
 
-	// Redirect I/O
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	// Redirect I/O, teeing and filtering output as configured. A detached target can't be
+	// relayed by the launcher, so it gets the log files or the null device directly.
+	var finishOutput func()
+	var err error
+	if l.Config.Detach {
+		finishOutput, err = openDetachedOutput(cmd, l.Config)
+	} else {
+		cmd.Stdin = l.Stdin
+		finishOutput, err = l.setupOutput(cmd, l.Stdout, l.Stderr)
+	}
+	if err != nil {
+		return err
+	}
+	defer finishOutput()
+
+	// Watch for output inactivity and memory use if configured
+	watchdog := newWatchdog(l.Config)
+	if watchdog != nil {
+		cmd.Stdout, cmd.Stderr = watchdog.watchOutput(cmd.Stdout), watchdog.watchOutput(cmd.Stderr)
+		// Processes the target leaves behind may keep its output open, and aren't waited
+		// for longer than the target would be
+		cmd.WaitDelay = l.Config.IdleTimeout
+	}
 
 	// Hide window if configured, platform-specific
 	if l.Config.HideTarget {
 		hideTargetWindow(cmd)
 	}
 
-	// Execute
-	err := cmd.Run()
+	// Start in the background if configured, platform-specific
+	if l.Config.Detach {
+		detachProcess(cmd)
+	}
+
+	// Run as another user if configured
+	if err := applyCredentials(cmd, l.Config); err != nil {
+		return err
+	}
+
+	// Pass on sockets of a systemd socket activation and configured inherited descriptors
+	socketActivated, err := passInheritedFds(cmd, l.Config)
+	if err != nil {
+		return err
+	}
+
+	// Confine to a namespace sandbox and restrict filesystem access if configured, holding
+	// the target back until resource limits and scheduling settings are applied
+	releaseTarget, err := applySandbox(cmd, l.Config, socketActivated)
+	if err != nil {
+		return err
+	}
+
+	// Become the subreaper of the target's process tree if processes left behind are handled
+	if err := trackDescendants(l.Config); err != nil {
+		releaseTarget(false)
+		return err
+	}
+
+	// Start, on a pseudo-terminal if configured
+	startTime := time.Now()
+	processStartMu.Lock()
+	restoreProcessSettings := prepareProcessStart(l.Config)
+	wait := cmd.Wait
+	if l.Config.Pty {
+		wait, err = startInPty(cmd)
+	} else {
+		err = cmd.Start()
+	}
+	restoreProcessSettings()
+	processStartMu.Unlock()
 	if err != nil {
-		return fmt.Errorf("failed to execute target: %v", err)
+		releaseTarget(false)
+		return fmt.Errorf("failed to execute target: %w", err)
+	}
+	// Deal with processes the target leaves behind once it has been waited for
+	defer handleDescendants(cmd.Process.Pid, l.Config)()
+
+	// Apply resource limits and scheduling settings before the target gets to run
+	if err := tuneStartedProcess(cmd.Process.Pid, l.Config); err != nil {
+		releaseTarget(false)
+		_ = cmd.Process.Kill()
+		_ = wait()
+		return err
+	}
+	releaseTarget(true)
+
+	// Record the running target for --proxylauncher-ps and --proxylauncher-stop. A detached
+	// target's record stays until it's found to be gone.
+	removeStatus := l.recordStatus(cmd, startTime)
+	if !l.Config.Detach {
+		defer removeStatus()
+	}
+
+	// A detached target is on its own from here on
+	if l.Config.Detach {
+		if l.pidFile != nil {
+			if err := l.pidFile.write(cmd.Process.Pid); err != nil {
+				_ = cmd.Process.Kill()
+				_ = wait()
+				return err
+			}
+		}
+		return cmd.Process.Release()
+	}
+
+	if watchdog != nil {
+		watchdog.start(cmd.Process)
+	}
+	err = wait()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		logf("processes left behind by the target still hold its output open, no longer relaying it")
+		err = nil
+	}
+	if watchdog != nil {
+		if killed := watchdog.stop(); killed != nil {
+			err = killed
+		}
+	}
+
+	// Report what the run cost, whether or not the target succeeded
+	if (l.Config.ReportUsage == "log" || l.Config.ReportUsage == "stderr") && cmd.ProcessState != nil {
+		reportUsage(l.Config, cmd.Args, cmd.ProcessState, time.Since(startTime))
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to execute target: %w", err)
 	}
 
 	return nil
 }
 
+// recordStatus writes the status record of the started target and returns a function removing
+// it. Failing to write it only gets logged, as it's not needed to run the target.
+func (l *Launcher) recordStatus(cmd *exec.Cmd, startTime time.Time) func() {
+	remove, err := writeStatusRecord(statusRecord{
+		Name:         fmt.Sprintf("%s-%d", l.Profile, cmd.Process.Pid),
+		Profile:      l.Profile,
+		ConfigPath:   l.ConfigPath,
+		Args:         cmd.Args,
+		Pid:          cmd.Process.Pid,
+		LauncherPid:  os.Getpid(),
+		StartTime:    startTime,
+		ProcessStart: processStartTime(cmd.Process.Pid),
+	})
+	if err != nil {
+		logf("warning: %v", err)
+		return func() {}
+	}
+	return remove
+}
+
+// setupOutput connects the command's stdout and stderr to the given writers, inserting
+// line filters and log file tees as configured. The returned function flushes pending
+// filtered output and closes the log files; call it once the command has finished.
+func (l *Launcher) setupOutput(cmd *exec.Cmd, stdout, stderr io.Writer) (func(), error) {
+	var logs *outputLogs
+	if hasOutputLogs(l.Config) {
+		var err error
+		logs, stdout, stderr, err = openOutputLogs(l.Config, stdout, stderr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Filters sit in front of the tee so log files receive the filtered output too
+	var filters []*lineFilterWriter
+	if len(l.Config.StdoutFilters) > 0 {
+		filter := &lineFilterWriter{out: stdout, filters: l.Config.StdoutFilters}
+		filters = append(filters, filter)
+		stdout = filter
+	}
+	if len(l.Config.StderrFilters) > 0 {
+		filter := &lineFilterWriter{out: stderr, filters: l.Config.StderrFilters}
+		filters = append(filters, filter)
+		stderr = filter
+	}
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	return func() {
+		for _, filter := range filters {
+			_ = filter.Flush()
+		}
+		if logs != nil {
+			_ = logs.Close()
+		}
+	}, nil
+}
+
 // parseArgs splits a string into command line arguments, respecting quoted sections
 func parseArgs(argsStr string) []string {
 	var args []string
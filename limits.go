@@ -0,0 +1,163 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rlimitInfinity marks a resource limit as unlimited
+const rlimitInfinity = math.MaxUint64
+
+// ResourceLimit is a soft and hard limit for one of the target's resources
+type ResourceLimit struct {
+	Soft uint64
+	Hard uint64
+}
+
+// resourceLimitNames lists the supported "limit.<name>" keys and how their values are parsed
+var resourceLimitNames = map[string]func(key, value string) (uint64, error){
+	"nofile": parseCount,
+	"as":     parseSizeLimit,
+	"cpu":    parseSeconds,
+	"core":   parseSizeLimit,
+	"nproc":  parseCount,
+}
+
+// parseResourceLimit parses a "limit.<name>" value: a single value used as both soft and
+// hard limit, or "soft:hard". Each value may be "unlimited".
+func parseResourceLimit(name, key, value string) (ResourceLimit, error) {
+	parse, known := resourceLimitNames[name]
+	if !known {
+		return ResourceLimit{}, fmt.Errorf("unknown resource limit in config: %s", key)
+	}
+
+	parseValue := func(v string) (uint64, error) {
+		v = strings.TrimSpace(v)
+		if strings.EqualFold(v, "unlimited") {
+			return rlimitInfinity, nil
+		}
+		return parse(key, v)
+	}
+
+	softStr, hardStr, separate := strings.Cut(value, ":")
+	soft, err := parseValue(softStr)
+	if err != nil {
+		return ResourceLimit{}, err
+	}
+	hard := soft
+	if separate {
+		if hard, err = parseValue(hardStr); err != nil {
+			return ResourceLimit{}, err
+		}
+		if soft > hard {
+			return ResourceLimit{}, fmt.Errorf("invalid %s value %q, soft limit exceeds hard limit", key, value)
+		}
+	}
+	return ResourceLimit{Soft: soft, Hard: hard}, nil
+}
+
+// parseCount parses a plain non-negative number
+func parseCount(key, value string) (uint64, error) {
+	count, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q, must be a number or 'unlimited'", key, value)
+	}
+	return count, nil
+}
+
+// parseSizeLimit parses a byte size like 512M
+func parseSizeLimit(key, value string) (uint64, error) {
+	size, err := parseSize(key, value)
+	return uint64(size), err
+}
+
+// parseSeconds parses a number of seconds or a duration like 90s or 1h30m
+func parseSeconds(key, value string) (uint64, error) {
+	if seconds, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return seconds, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid %s value %q, must be a number of seconds, a duration like 1h30m or 'unlimited'", key, value)
+	}
+	return uint64(duration.Round(time.Second) / time.Second), nil
+}
+
+// parseUmask parses an octal file mode creation mask like 022
+func parseUmask(key, value string) (int, error) {
+	umask, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || umask > 0777 {
+		return 0, fmt.Errorf("invalid %s value %q, must be an octal mask like 022", key, value)
+	}
+	return int(umask), nil
+}
+
+// maxCPUs is how many CPUs a CPU list can name, as many as the kernel's CPU set holds
+const maxCPUs = 1024
+
+// CPUSet holds the CPUs of a CPU list, one bit per CPU number
+type CPUSet [maxCPUs / 64]uint64
+
+// add puts the CPU into the set
+func (s *CPUSet) add(cpu int) {
+	s[cpu/64] |= 1 << (cpu % 64)
+}
+
+// isEmpty reports whether the set has no CPUs, as when cpuAffinity isn't configured
+func (s *CPUSet) isEmpty() bool {
+	return *s == CPUSet{}
+}
+
+// cpus returns the CPU numbers in the set, in ascending order
+func (s *CPUSet) cpus() []int {
+	var cpus []int
+	for cpu := range maxCPUs {
+		if s[cpu/64]&(1<<(cpu%64)) != 0 {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
+
+// parseCPUList parses a CPU list like "0-3,6" into the set of CPUs it contains
+func parseCPUList(key, value string) (CPUSet, error) {
+	invalid := fmt.Errorf("invalid %s value %q, must be a CPU list like '0-3,6' of CPUs below %d", key, value, maxCPUs)
+
+	var set CPUSet
+	for _, part := range strings.Split(value, ",") {
+		firstStr, lastStr, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		first, err := strconv.Atoi(firstStr)
+		if err != nil || first < 0 || first >= maxCPUs {
+			return CPUSet{}, invalid
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(lastStr); err != nil || last < first || last >= maxCPUs {
+				return CPUSet{}, invalid
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			set.add(cpu)
+		}
+	}
+	return set, nil
+}
+
+// parseIntRange parses a whole number between min and max
+func parseIntRange(key, value string, min, max int) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min || number > max {
+		return 0, fmt.Errorf("invalid %s value %q, must be a number between %d and %d", key, value, min, max)
+	}
+	return number, nil
+}
+
+// hasLinuxProcessSettings reports whether any setting only applied on Linux is configured
+func (c *Configuration) hasLinuxProcessSettings() bool {
+	return len(c.Limits) > 0 || c.Nice != nil || c.Umask != nil ||
+		!c.CPUAffinity.isEmpty() || c.IOClass != "" || c.OOMScoreAdj != nil
+}
@@ -0,0 +1,193 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// remoteAddrEnvVar tells a target started for a connection who it's talking to
+const remoteAddrEnvVar = "PROXYLAUNCHER_REMOTE_ADDR"
+
+// parseListenAddress splits a listen value like "tcp:127.0.0.1:8080" or "unix:/run/tool.sock"
+// into its network and address
+func parseListenAddress(key, value string) (network, address string, err error) {
+	network, address, _ = strings.Cut(value, ":")
+	network = strings.ToLower(network)
+	switch {
+	case network == "tcp" && address != "":
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return "", "", fmt.Errorf("invalid %s value %q, %v", key, value, err)
+		}
+	case network == "unix" && address != "":
+	default:
+		return "", "", fmt.Errorf("invalid %s value %q, must be 'tcp:<host>:<port>' or 'unix:<path>'", key, value)
+	}
+	return network, address, nil
+}
+
+// checkListenSettings rejects settings that can't work when a target runs per connection
+func (c *Configuration) checkListenSettings() error {
+	if c.Listen == "" {
+		if c.MaxConnections > 0 {
+			return fmt.Errorf("maxConnections requires listen")
+		}
+		return nil
+	}
+	conflicts := []struct {
+		key string
+		set bool
+	}{
+		{"detach", c.Detach},
+		// Every connection's launch would find the PID file of the one before
+		{"pidFile", c.PidFile != ""},
+		// Reaping and finding leftovers works on all of the launcher's children, which would
+		// include the targets of other connections
+		{"descendants", c.Descendants == "wait" || c.Descendants == "kill"},
+		// Every connection opens the output logs, which would truncate or rotate them under
+		// the targets of other connections
+		{"outputLogMode=truncate", c.OutputLogMode == "truncate"},
+		{"outputLogMaxSize", c.OutputLogMaxSize > 0},
+	}
+	for _, conflict := range conflicts {
+		if conflict.set {
+			return fmt.Errorf("%s can't be used with listen", conflict.key)
+		}
+	}
+	return nil
+}
+
+// Serve listens on the configured address and launches the target for every connection,
+// with the connection as its stdin and stdout. It only returns if listening fails.
+func (l *Launcher) Serve() error {
+	listener, err := listen(l.Config.Listen)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	logf("listening on %s", l.Config.Listen)
+	return l.serve(listener)
+}
+
+// listen opens the listening socket, replacing a Unix socket left behind by an earlier run
+func listen(value string) (net.Listener, error) {
+	network, address, err := parseListenAddress("listen", value)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		if info, err := os.Stat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if conn, err := net.Dial("unix", address); err == nil {
+				conn.Close()
+				return nil, fmt.Errorf("failed to listen on %s: another process is listening on it", value)
+			}
+			_ = os.Remove(address)
+		}
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", value, err)
+	}
+	return listener, nil
+}
+
+// serve accepts connections until the listener is closed, handling each in its own goroutine.
+// With maxConnections set, further connections wait in the backlog until a target exits.
+func (l *Launcher) serve(listener net.Listener) error {
+	var slots chan struct{}
+	if l.Config.MaxConnections > 0 {
+		slots = make(chan struct{}, l.Config.MaxConnections)
+	}
+
+	var connections sync.WaitGroup
+	defer connections.Wait()
+	var retryDelay time.Duration
+	for {
+		if slots != nil {
+			slots <- struct{}{}
+		}
+		conn, err := listener.Accept()
+		if err != nil {
+			if slots != nil {
+				<-slots
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			if !temporaryAcceptError(err) {
+				return fmt.Errorf("failed to accept connection: %v", err)
+			}
+			// Keep serving after a pause growing like net/http's
+			retryDelay = min(max(2*retryDelay, 5*time.Millisecond), time.Second)
+			logf("failed to accept connection: %v, retrying in %v", err, retryDelay)
+			time.Sleep(retryDelay)
+			continue
+		}
+		retryDelay = 0
+
+		connections.Add(1)
+		go func() {
+			defer connections.Done()
+			if slots != nil {
+				defer func() { <-slots }()
+			}
+			l.serveConnection(conn)
+		}()
+	}
+}
+
+// temporaryAcceptError reports whether accepting connections failed for a reason that passes,
+// like running out of file descriptors or a connection aborted before it was accepted
+func temporaryAcceptError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	for _, errno := range []syscall.Errno{syscall.EMFILE, syscall.ENFILE, syscall.ENOBUFS, syscall.ENOMEM, syscall.ECONNABORTED} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveConnection launches the target for a connection and closes it once the target exited
+func (l *Launcher) serveConnection(conn net.Conn) {
+	defer conn.Close()
+	// Unix socket clients are usually unnamed
+	remote := "local"
+	if addr := conn.RemoteAddr(); addr != nil && addr.String() != "" && addr.String() != "@" {
+		remote = addr.String()
+	}
+	logf("connection from %s accepted", remote)
+	start := time.Now()
+
+	// Hand the socket itself to the target where possible, so it sees end of input and can
+	// shut down its side; otherwise the launcher relays it
+	var stream io.ReadWriter = conn
+	if fileConn, ok := conn.(interface{ File() (*os.File, error) }); ok {
+		if file, err := fileConn.File(); err == nil {
+			defer file.Close()
+			stream = file
+		}
+	}
+
+	launcher := *l
+	launcher.Stdin, launcher.Stdout = stream, stream
+	launcher.Env = append(append([]string{}, l.Env...), remoteAddrEnvVar+"="+remote)
+
+	err := launcher.Launch()
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil && exitCodeOf(err) < 0 {
+		logf("connection from %s failed after %v: %v", remote, elapsed, err)
+		return
+	}
+	logf("connection from %s finished after %v, target exited with code %d", remote, elapsed, exitCodeOf(err))
+}
@@ -0,0 +1,43 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// launcherLogPrefix marks ProxyLauncher's messages on stderr
+const launcherLogPrefix = "proxylauncher: "
+
+// launcherLog receives ProxyLauncher's own diagnostic messages, as opposed to the target's output
+var launcherLog = log.New(os.Stderr, launcherLogPrefix, 0)
+
+// logf writes a diagnostic message to the launcher log
+func logf(format string, args ...any) {
+	launcherLog.Printf(format, args...)
+}
+
+// openLauncherLog sends the launcher log to a file instead of stderr, appending timestamped
+// messages. The returned function closes the file and restores logging to stderr.
+func openLauncherLog(path string) (func(), error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening log file: %v", err)
+	}
+	launcherLog.SetOutput(file)
+	launcherLog.SetPrefix("")
+	launcherLog.SetFlags(log.LstdFlags | log.Lmicroseconds)
+
+	return func() {
+		launcherLog.SetOutput(os.Stderr)
+		launcherLog.SetPrefix(launcherLogPrefix)
+		launcherLog.SetFlags(0)
+		file.Close()
+	}, nil
+}
+
+// logsToStderr reports whether the launcher log is written to stderr
+func logsToStderr() bool {
+	return launcherLog.Writer() == os.Stderr
+}
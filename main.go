@@ -2,11 +2,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 // Function variables that can be overridden in tests
@@ -14,6 +18,9 @@ var (
 	// execCommand is a variable wrapping exec.Command for testing
 	execCommand = exec.Command
 
+	// execCommandContext is a variable wrapping exec.CommandContext for testing
+	execCommandContext = exec.CommandContext
+
 	// fileExistsFunc is a function to check if a file exists
 	fileExistsFunc = func(path string) bool {
 		info, err := os.Stat(path)
@@ -26,12 +33,41 @@ var (
 )
 
 func main() {
-	// Parse command-line flags
-	configPath := flag.String("config", "", "Path to config file")
-	flag.Parse()
+	// When re-executed to set up a sandbox, this executes the target instead of returning
+	runSandboxInitIfRequested()
+
+	// Note which descriptors were passed to the launcher before it opens any itself
+	recordInheritedFds()
+
+	// Parse the launcher's own command-line flags, the rest is for the target
+	flags, targetArgs, err := parseLauncherFlags(os.Args[1:])
+	if err != nil {
+		showErrorMessageBox(err.Error())
+		os.Exit(2)
+	}
+
+	// List or stop running targets instead of launching if requested
+	if flags.ps {
+		records, err := listStatusRecords()
+		if err != nil {
+			showErrorMessageBox(err.Error())
+			return
+		}
+		fmt.Print(formatStatusRecords(records))
+		return
+	}
+	if flags.stop != "" {
+		summary, err := stopTargets(flags.stop)
+		if err != nil {
+			showErrorMessageBox(err.Error())
+			return
+		}
+		fmt.Print(summary)
+		return
+	}
 
 	// Determine config path (defaults to executable directory)
-	cfgPath := *configPath
+	cfgPath := flags.configPath
 	if cfgPath == "" {
 		execPath, err := os.Executable()
 		if err != nil {
@@ -70,6 +106,17 @@ func main() {
 		return
 	}
 
+	// Pin the target checksums instead of launching if requested
+	if flags.pin {
+		summary, err := pinTargetChecksums(cfgPath)
+		if err != nil {
+			showErrorMessageBox(err.Error())
+			return
+		}
+		showInfoMessageBox(summary)
+		return
+	}
+
 	// Load configuration
 	config, err := loadConfig(cfgPath)
 	if err != nil {
@@ -77,12 +124,40 @@ func main() {
 		return
 	}
 
+	// Write the launcher's own messages to the log file from here on, if configured
+	if config.LogFile != "" {
+		closeLog, err := openLauncherLog(config.LogFile)
+		if err != nil {
+			showErrorMessageBox(err.Error())
+			return
+		}
+		defer closeLog()
+	}
+
 	// Create launcher
 	launcher := NewLauncher(config)
+	launcher.ConfigPath = cfgPath
+	launcher.Args = targetArgs
+
+	// Serve connections instead of launching once in listener mode
+	if config.Listen != "" {
+		if err := launcher.Serve(); err != nil {
+			showErrorMessageBox(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 
-	// Launch target
+	// Launch target, ending like it did if it ran but failed
 	if err := launcher.Launch(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			mirrorTerminationSignal(exitErr.ProcessState)
+		}
 		showErrorMessageBox(err.Error())
+		if code := exitCodeOf(err); code > 0 {
+			os.Exit(code)
+		}
 	}
 }
 
@@ -90,3 +165,53 @@ func main() {
 func fileExists(path string) bool {
 	return fileExistsFunc(path)
 }
+
+// launcherFlags holds the launcher's own command-line flags
+type launcherFlags struct {
+	configPath string
+	pin        bool
+	ps         bool
+	stop       string
+}
+
+// parseLauncherFlags takes the launcher's own flags from the start of the command line and
+// returns the remaining arguments for the target. It stops at the first argument that isn't
+// one of them, or after "--", so the target's own flags are passed on untouched.
+func parseLauncherFlags(args []string) (launcherFlags, []string, error) {
+	var flags launcherFlags
+	flagSet := flag.NewFlagSet("proxylauncher", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	flagSet.StringVar(&flags.configPath, "config", "", "Path to config file")
+	flagSet.BoolVar(&flags.pin, "proxylauncher-pin", false, "Write the current target's SHA-256 checksum into the config file")
+	flagSet.BoolVar(&flags.ps, "proxylauncher-ps", false, "List the targets running through ProxyLauncher")
+	flagSet.StringVar(&flags.stop, "proxylauncher-stop", "", "Stop the running targets with this name or profile")
+
+	// Find where the launcher's flags end, a flag without "=" taking the next argument as value
+	// unless it's a boolean
+	end := 0
+	for end < len(args) {
+		arg := args[end]
+		if arg == "--" {
+			end++
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		found := flagSet.Lookup(name)
+		if found == nil {
+			break
+		}
+		end++
+		if _, isBool := found.Value.(interface{ IsBoolFlag() bool }); !hasValue && !isBool {
+			end++
+		}
+	}
+	end = min(end, len(args))
+
+	if err := flagSet.Parse(args[:end]); err != nil {
+		return flags, nil, fmt.Errorf("invalid command line: %v", err)
+	}
+	return flags, args[end:], nil
+}
@@ -1,14 +1,26 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 // setupTestEnv creates a test environment with mockable message functions
@@ -39,6 +51,29 @@ func restoreTestEnv(origErrorFunc, origInfoFunc func(string) error) {
 	showInfoMessageFunc = origInfoFunc
 }
 
+// writeTempConfig writes config content to a temporary file and opens it for parsing
+func writeTempConfig(t *testing.T, content string) *os.File {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "test.cfg")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+	file, err := os.Open(configPath)
+	if err != nil {
+		t.Fatalf("Failed to open test config file: %v", err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+// successCommand returns a command that exits successfully without output on any platform
+func successCommand() *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/c", "exit", "0")
+	}
+	return exec.Command("true")
+}
+
 // TestParseConfig tests the configuration parsing logic
 func TestParseConfig(t *testing.T) {
 	tests := []struct {
@@ -65,6 +100,37 @@ hideTarget = true
 				HideTarget:     true,
 			},
 		},
+		{
+			name: "Output Logs",
+			content: `
+target = "app.exe"
+stdoutLog = out.log
+stderrLog = err.log
+combinedLog = all.log
+outputLogMode = Truncate
+outputLogMaxSize = 10M
+outputLogTimestamps = yes
+`,
+			expectError: false,
+			expected: Configuration{
+				Target:              "app.exe",
+				StdoutLog:           "out.log",
+				StderrLog:           "err.log",
+				CombinedLog:         "all.log",
+				OutputLogMode:       "truncate",
+				OutputLogMaxSize:    10 << 20,
+				OutputLogTimestamps: true,
+			},
+		},
+		{
+			name: "Invalid OutputLogMode",
+			content: `
+target = "app.exe"
+outputLogMode = rotate
+`,
+			expectError: true,
+			errorSubstr: "invalid outputLogMode value",
+		},
 		{
 			name: "Missing Target",
 			content: `
@@ -183,6 +249,16 @@ target = "app.exe"
 				if config.HideTarget != tc.expected.HideTarget {
 					t.Errorf("Expected HideTarget=%v, got %v", tc.expected.HideTarget, config.HideTarget)
 				}
+				if config.StdoutLog != tc.expected.StdoutLog || config.StderrLog != tc.expected.StderrLog || config.CombinedLog != tc.expected.CombinedLog {
+					t.Errorf("Expected logs %q/%q/%q, got %q/%q/%q", tc.expected.StdoutLog, tc.expected.StderrLog, tc.expected.CombinedLog,
+						config.StdoutLog, config.StderrLog, config.CombinedLog)
+				}
+				if config.OutputLogMode != tc.expected.OutputLogMode || config.OutputLogMaxSize != tc.expected.OutputLogMaxSize ||
+					config.OutputLogTimestamps != tc.expected.OutputLogTimestamps {
+					t.Errorf("Expected output log settings %q/%d/%v, got %q/%d/%v",
+						tc.expected.OutputLogMode, tc.expected.OutputLogMaxSize, tc.expected.OutputLogTimestamps,
+						config.OutputLogMode, config.OutputLogMaxSize, config.OutputLogTimestamps)
+				}
 			}
 		})
 	}
@@ -371,3 +447,1665 @@ func TestFileExists(t *testing.T) {
 		t.Errorf("Non-existent file %s should not exist", nonExistentFile)
 	}
 }
+
+// TestParseSize tests parsing of size values with suffixes
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    int64
+		expectError bool
+	}{
+		{"1024", 1024, false},
+		{"512K", 512 << 10, false},
+		{"10M", 10 << 20, false},
+		{"1g", 1 << 30, false},
+		{"2MB", 2 << 20, false},
+		{"", 0, true},
+		{"-1", 0, true},
+		{"lots", 0, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := parseSize("size", tc.input)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %d", tc.input, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("Expected %d, got %d", tc.expected, result)
+			}
+		})
+	}
+}
+
+// TestRotatingFile tests that log files are rotated once they exceed their maximum size
+func TestRotatingFile(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "out.log")
+
+	file, err := openRotatingFile(logPath, false, 10)
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	file.Write([]byte("first\n"))
+	file.Write([]byte("second\n"))
+	file.Close()
+
+	current, _ := os.ReadFile(logPath)
+	if string(current) != "second\n" {
+		t.Errorf("Expected current log 'second\\n', got %q", current)
+	}
+	rotated, _ := os.ReadFile(logPath + ".1")
+	if string(rotated) != "first\n" {
+		t.Errorf("Expected rotated log 'first\\n', got %q", rotated)
+	}
+
+	// Reopening with truncate should discard the previous content
+	file, err = openRotatingFile(logPath, true, 0)
+	if err != nil {
+		t.Fatalf("Failed to reopen log file: %v", err)
+	}
+	file.Write([]byte("third\n"))
+	file.Close()
+
+	current, _ = os.ReadFile(logPath)
+	if string(current) != "third\n" {
+		t.Errorf("Expected truncated log 'third\\n', got %q", current)
+	}
+
+	// Output keeps going to the current file if it can't be rotated
+	os.Remove(logPath + ".1")
+	os.MkdirAll(filepath.Join(logPath+".1", "blocked"), 0755)
+	file, err = openRotatingFile(logPath, false, 10)
+	if err != nil {
+		t.Fatalf("Failed to reopen log file: %v", err)
+	}
+	file.Write([]byte("fourth\n"))
+	file.Write([]byte("fifth\n"))
+	file.Close()
+
+	current, _ = os.ReadFile(logPath)
+	if string(current) != "third\nfourth\nfifth\n" {
+		t.Errorf("Expected log to be kept after failed rotation, got %q", current)
+	}
+}
+
+// TestTimestampWriter tests that every line gets a timestamp prefix, including lines split across writes
+func TestTimestampWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer := &timestampWriter{out: &buf, atLineStart: true}
+	writer.Write([]byte("one\ntw"))
+	writer.Write([]byte("o\nthree\n"))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	expected := []string{"one", "two", "three"}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d: %q", len(expected), len(lines), buf.String())
+	}
+	for i, line := range lines {
+		if len(line) != len(timestampFormat)+len(expected[i]) || !strings.HasSuffix(line, expected[i]) {
+			t.Errorf("Line %d not timestamped as expected: %q", i, line)
+		}
+	}
+}
+
+// TestLaunchOutputLogs tests that the target's output is teed into the configured log files
+func TestLaunchOutputLogs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses a POSIX shell")
+	}
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "echo out; echo err >&2")
+	}
+
+	tempDir := t.TempDir()
+	launcher := NewLauncher(&Configuration{
+		Target:      "sh",
+		StdoutLog:   filepath.Join(tempDir, "stdout.log"),
+		StderrLog:   filepath.Join(tempDir, "stderr.log"),
+		CombinedLog: filepath.Join(tempDir, "combined.log"),
+	})
+	if err := launcher.Launch(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for name, expected := range map[string]string{
+		"stdout.log": "out\n",
+		"stderr.log": "err\n",
+	} {
+		content, _ := os.ReadFile(filepath.Join(tempDir, name))
+		if string(content) != expected {
+			t.Errorf("Expected %s to contain %q, got %q", name, expected, content)
+		}
+	}
+	combined, _ := os.ReadFile(filepath.Join(tempDir, "combined.log"))
+	if !strings.Contains(string(combined), "out\n") || !strings.Contains(string(combined), "err\n") {
+		t.Errorf("Expected combined.log to contain both streams, got %q", combined)
+	}
+}
+
+// TestOutputFilters tests parsing of filter rules and their streaming application
+func TestOutputFilters(t *testing.T) {
+	content := `
+target = "app.exe"
+stdoutFilter.2 = replace password=\S+ => password=***
+stdoutFilter.1 = drop ^WARNING
+stderrFilter.1 = replace (\d+)ms => ${1} ms
+`
+	file := writeTempConfig(t, content)
+	config, err := parseConfig(file)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(config.StdoutFilters) != 2 || len(config.StderrFilters) != 1 {
+		t.Fatalf("Expected 2 stdout and 1 stderr filters, got %d and %d", len(config.StdoutFilters), len(config.StderrFilters))
+	}
+	if !config.StdoutFilters[0].Drop {
+		t.Errorf("Expected stdout filters to be ordered by index")
+	}
+
+	var stdout bytes.Buffer
+	writer := &lineFilterWriter{out: &stdout, filters: config.StdoutFilters}
+	writer.Write([]byte("WARNING: deprecated\nlogin password=hun"))
+	writer.Write([]byte("ter2 ok\r\nWARNING: again\nunterminated"))
+	writer.Flush()
+
+	expected := "login password=*** ok\r\nunterminated"
+	if stdout.String() != expected {
+		t.Errorf("Expected filtered stdout %q, got %q", expected, stdout.String())
+	}
+
+	var stderr bytes.Buffer
+	writer = &lineFilterWriter{out: &stderr, filters: config.StderrFilters}
+	writer.Write([]byte("took 15ms\n"))
+	if stderr.String() != "took 15 ms\n" {
+		t.Errorf("Expected filtered stderr %q, got %q", "took 15 ms\n", stderr.String())
+	}
+
+	// Invalid rules
+	for _, rule := range []string{"hide foo", "replace foo", "drop (unclosed", "drop"} {
+		if _, err := parseOutputFilter("stdoutFilter.1", rule); err == nil {
+			t.Errorf("Expected error for rule %q, got nil", rule)
+		}
+	}
+}
+
+// TestLaunchPty tests that the target runs on a terminal and its exit status is still reported
+func TestLaunchPty(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("pty mode is only supported on Linux")
+	}
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "if [ -t 0 ] && [ -t 1 ]; then echo on a tty; fi; exit 3")
+	}
+
+	logPath := filepath.Join(t.TempDir(), "stdout.log")
+	launcher := NewLauncher(&Configuration{Target: "sh", Pty: true, StdoutLog: logPath})
+	err := launcher.Launch()
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("Expected exit code 3, got: %v", err)
+	}
+	content, _ := os.ReadFile(logPath)
+	if string(content) != "on a tty\n" {
+		t.Errorf("Expected output %q, got %q", "on a tty\n", content)
+	}
+
+	// Input is no longer read once the target exited
+	reader, writer, _ := os.Pipe()
+	defer reader.Close()
+	defer writer.Close()
+	launcher.Stdin = reader
+	launcher.Config.StdoutLog = ""
+	launcher.Stdout = io.Discard
+	if err := launcher.Launch(); exitCodeOf(err) != 3 {
+		t.Errorf("Expected exit code 3, got: %v", err)
+	}
+	fmt.Fprintln(writer, "next")
+	_ = reader.SetReadDeadline(time.Now().Add(time.Second))
+	if line, _ := bufio.NewReader(reader).ReadString('\n'); line != "next\n" {
+		t.Errorf("Expected input after the target exited to be left alone, got %q", line)
+	}
+}
+
+// TestReportUsage tests that the resource usage of the target is written to the launcher log file
+func TestReportUsage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses a POSIX shell")
+	}
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "exit 3")
+	}
+
+	logPath := filepath.Join(t.TempDir(), "launcher.log")
+	closeLog, err := openLauncherLog(logPath)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	launcher := NewLauncher(&Configuration{Target: "sh", ReportUsage: "log"})
+	err = launcher.Launch()
+	closeLog()
+
+	if exitCodeOf(err) != 3 {
+		t.Errorf("Expected exit code 3, got: %v", err)
+	}
+	if !logsToStderr() {
+		t.Errorf("Expected launcher log to be restored to stderr")
+	}
+	content, _ := os.ReadFile(logPath)
+	for _, expected := range []string{"resource usage of target:", `Command being timed: "sh -c exit 3"`,
+		"Elapsed (wall clock) time:", "Maximum resident set size (kbytes):", "Exit status: 3"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected log to contain %q, got:\n%s", expected, content)
+		}
+	}
+}
+
+// TestWatchdogs tests that the target is killed when it stops producing output or uses too much memory
+func TestWatchdogs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses a POSIX shell")
+	}
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "echo started; sleep 0.2; echo still alive; exec sleep 10")
+	}
+
+	tests := []struct {
+		name     string
+		config   Configuration
+		exitCode int
+	}{
+		{"Idle Timeout", Configuration{IdleTimeout: 500 * time.Millisecond}, exitCodeIdleTimeout},
+		{"Max RSS", Configuration{MaxRSS: 1}, exitCodeMaxRSS},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.config.MaxRSS > 0 && runtime.GOOS != "linux" {
+				t.Skip("maxRSS is only supported on Linux")
+			}
+
+			tc.config.Target = "sh"
+			tc.config.StdoutLog = filepath.Join(t.TempDir(), "stdout.log")
+			start := time.Now()
+			err := NewLauncher(&tc.config).Launch()
+
+			if exitCodeOf(err) != tc.exitCode {
+				t.Errorf("Expected exit code %d, got: %v", tc.exitCode, err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Expected target to be killed early, took %v", elapsed)
+			}
+		})
+	}
+
+	// Regular output keeps the target alive
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "for i in 1 2 3 4 5; do echo $i; sleep 0.1; done")
+	}
+	config := &Configuration{Target: "sh", IdleTimeout: 400 * time.Millisecond, StdoutLog: filepath.Join(t.TempDir(), "stdout.log")}
+	if err := NewLauncher(config).Launch(); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	// A process left behind holding the output doesn't keep the launcher waiting
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "sleep 5 & echo started")
+	}
+	config = &Configuration{Target: "sh", IdleTimeout: 300 * time.Millisecond, StdoutLog: filepath.Join(t.TempDir(), "stdout.log")}
+	start := time.Now()
+	if err := NewLauncher(config).Launch(); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected launcher to return once the target exited, took %v", elapsed)
+	}
+
+	// Output is only watched with an idle timeout, so maxRSS alone leaves a terminal to the target
+	if watchdog := newWatchdog(&Configuration{MaxRSS: 1}); watchdog != nil {
+		if out := watchdog.watchOutput(os.Stdout); out != io.Writer(os.Stdout) {
+			t.Errorf("Expected output to be left alone without idleTimeout, got: %T", out)
+		}
+	}
+}
+
+// TestDetach tests that a detached target keeps running in the background with a PID file
+func TestDetach(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses a POSIX shell")
+	}
+
+	tempDir := t.TempDir()
+	t.Setenv(runtimeDirEnvVar, tempDir)
+	pidFile := filepath.Join(tempDir, "target.pid")
+	logPath := filepath.Join(tempDir, "stdout.log")
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "echo started; sleep 0.5; echo done")
+	}
+
+	config := &Configuration{Target: "sh", Detach: true, PidFile: pidFile, StdoutLog: logPath}
+	start := time.Now()
+	if err := NewLauncher(config).Launch(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+		t.Errorf("Expected launcher to return right away, took %v", elapsed)
+	}
+
+	content, _ := os.ReadFile(pidFile)
+	pid, running := pidFileRunning(string(content))
+	if !running {
+		t.Fatalf("Expected PID file of the running target, got %q", content)
+	}
+
+	// A second launch is refused while the target runs
+	err := NewLauncher(config).Launch()
+	if err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("Expected already running error, got: %v", err)
+	}
+
+	// The launcher would have exited in real use, so collect the target here
+	if process, err := os.FindProcess(pid); err == nil {
+		_, _ = process.Wait()
+	}
+	output, _ := os.ReadFile(logPath)
+	if string(output) != "started\ndone\n" {
+		t.Errorf("Expected target output in log, got %q", output)
+	}
+
+	// The stale PID file doesn't block the next launch
+	if err := NewLauncher(config).Launch(); err != nil {
+		t.Errorf("Expected stale PID file to be replaced, got: %v", err)
+	}
+	content, _ = os.ReadFile(pidFile)
+	newPid, _ := pidFileRunning(string(content))
+	if newPid == pid || newPid == 0 {
+		t.Fatalf("Expected new PID in PID file, got %q", content)
+	}
+	if process, err := os.FindProcess(newPid); err == nil {
+		_, _ = process.Wait()
+	}
+
+	// A PID reused by another process doesn't block the launch
+	if processStartTime(os.Getpid()) != "" {
+		os.WriteFile(pidFile, []byte(fmt.Sprintf("%d\n1\n", os.Getpid())), 0644)
+		if err := NewLauncher(config).Launch(); err != nil {
+			t.Errorf("Expected PID file of a reused PID to be replaced, got: %v", err)
+		}
+		content, _ = os.ReadFile(pidFile)
+		if newPid, _ = pidFileRunning(string(content)); newPid == os.Getpid() || newPid == 0 {
+			t.Fatalf("Expected new PID in PID file, got %q", content)
+		}
+		if process, err := os.FindProcess(newPid); err == nil {
+			_, _ = process.Wait()
+		}
+	}
+
+	// Nor can a launch start while another one holds the PID file, which is removed again
+	// if no target was started
+	claimed, err := claimPidFile(filepath.Join(tempDir, "claimed.pid"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	config.PidFile = claimed.path
+	if err := NewLauncher(config).Launch(); err == nil || !strings.Contains(err.Error(), "being launched") {
+		t.Errorf("Expected launch in progress error, got: %v", err)
+	}
+	claimed.close()
+	if _, err := os.Stat(claimed.path); err == nil {
+		t.Errorf("Expected unused PID file to be removed")
+	}
+}
+
+// TestListener tests that the target is launched per connection with the socket as stdin and stdout
+func TestListener(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses a POSIX shell")
+	}
+	t.Setenv(runtimeDirEnvVar, t.TempDir())
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", `read line; echo "$line from $PROXYLAUNCHER_REMOTE_ADDR"; exit 3`)
+	}
+
+	listener, err := listen("tcp:127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	launcher := NewLauncher(&Configuration{Target: "sh", Listen: "tcp:127.0.0.1:0", MaxConnections: 1})
+	served := make(chan error, 1)
+	go func() { served <- launcher.serve(listener) }()
+
+	// The second connection waits for the first one's target, as only one is served at a time
+	var conns []net.Conn
+	for range 2 {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	_ = conns[1].SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, err := conns[1].Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected second connection to wait, got: %v", err)
+	}
+	_ = conns[1].SetReadDeadline(time.Time{})
+
+	for i, conn := range conns {
+		fmt.Fprintf(conn, "hello %d\n", i)
+		output, _ := io.ReadAll(conn)
+		expected := fmt.Sprintf("hello %d from %s\n", i, conn.LocalAddr())
+		if string(output) != expected {
+			t.Errorf("Expected %q, got %q", expected, output)
+		}
+	}
+
+	listener.Close()
+	if err := <-served; err != nil {
+		t.Errorf("Expected no error after closing the listener, got: %v", err)
+	}
+
+	// Failing to accept a connection doesn't stop serving, nor use up a slot
+	listener, err = listen("tcp:127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	go func() { served <- launcher.serve(&failingListener{Listener: listener, failures: 3}) }()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintln(conn, "hello again")
+	if output, _ := io.ReadAll(conn); !strings.HasPrefix(string(output), "hello again from ") {
+		t.Errorf("Expected connection to be served after accept errors, got %q", output)
+	}
+	listener.Close()
+	if err := <-served; err != nil {
+		t.Errorf("Expected no error after closing the listener, got: %v", err)
+	}
+
+	// Errors that don't pass end serving
+	failing := &failingListener{Listener: listener, failures: 1, err: syscall.EINVAL}
+	if err := launcher.serve(failing); err == nil || !strings.Contains(err.Error(), "invalid argument") {
+		t.Errorf("Expected accept error, got: %v", err)
+	}
+}
+
+// failingListener fails to accept the given number of connections before accepting them,
+// with EMFILE unless another error is set
+type failingListener struct {
+	net.Listener
+	failures int
+	err      error
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	if l.failures > 0 {
+		l.failures--
+		if l.err != nil {
+			return nil, l.err
+		}
+		return nil, syscall.EMFILE
+	}
+	return l.Listener.Accept()
+}
+
+// TestStatusRecords tests that running targets are recorded, listed and can be stopped
+func TestStatusRecords(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses a POSIX shell")
+	}
+
+	runtimeDir := t.TempDir()
+	t.Setenv(runtimeDirEnvVar, runtimeDir)
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+
+	// The record appears while the target runs and is removed afterwards
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "until cat "+runtimeDir+"/*.json 2>/dev/null; do sleep 0.05; done")
+	}
+	logPath := filepath.Join(t.TempDir(), "stdout.log")
+	launcher := NewLauncher(&Configuration{Target: "sh", StdoutLog: logPath})
+	launcher.ConfigPath = "/etc/app.cfg"
+	if err := launcher.Launch(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	content, _ := os.ReadFile(logPath)
+	for _, expected := range []string{`"profile": "` + launcher.Profile + `"`, `"configPath": "/etc/app.cfg"`, `"launcherPid": ` + strconv.Itoa(os.Getpid())} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected status record to contain %q, got:\n%s", expected, content)
+		}
+	}
+	if records, _ := filepath.Glob(filepath.Join(runtimeDir, "*.json")); len(records) > 0 {
+		t.Errorf("Expected status record to be removed, found %v", records)
+	}
+
+	// Records of targets that are gone are pruned
+	stale := `{"name": "gone-1", "profile": "gone", "pid": 999999999}`
+	os.WriteFile(filepath.Join(runtimeDir, "gone-1.json"), []byte(stale), 0644)
+
+	// So are records whose PID was reused by another process, which is never signaled
+	if processStartTime(os.Getpid()) != "" {
+		reused := fmt.Sprintf(`{"name": "sleeper-1", "profile": "sleeper", "pid": %d, "processStart": "1"}`, os.Getpid())
+		os.WriteFile(filepath.Join(runtimeDir, "sleeper-1.json"), []byte(reused), 0644)
+	}
+
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sleep", "10")
+	}
+	launcher = NewLauncher(&Configuration{Target: "sleep", Detach: true})
+	launcher.Profile = "sleeper"
+	if err := launcher.Launch(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	records, err := listStatusRecords()
+	if err != nil || len(records) != 1 || records[0].Profile != "sleeper" {
+		t.Fatalf("Expected only the sleeper record, got %+v (%v)", records, err)
+	}
+	for _, stale := range []string{"gone-1.json", "sleeper-1.json"} {
+		if _, err := os.Stat(filepath.Join(runtimeDir, stale)); err == nil {
+			t.Errorf("Expected stale record %s to be pruned", stale)
+		}
+	}
+	if runtime.GOOS == "linux" && records[0].ProcessStart != processStartTime(records[0].Pid) {
+		t.Errorf("Expected process start time %q to be recorded, got %q", processStartTime(records[0].Pid), records[0].ProcessStart)
+	}
+	if table := formatStatusRecords(records); !strings.Contains(table, records[0].Name) || !strings.Contains(table, "sleep 10") {
+		t.Errorf("Unexpected listing:\n%s", table)
+	}
+
+	// Stopping by profile signals the target
+	if _, err := stopTargets("nothing"); err == nil {
+		t.Errorf("Expected error for unknown name")
+	}
+	if _, err := stopTargets("sleeper"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	process, _ := os.FindProcess(records[0].Pid)
+	state, _ := process.Wait()
+	if status, ok := state.Sys().(syscall.WaitStatus); !ok || status.Signal() != syscall.SIGTERM {
+		t.Errorf("Expected target to be terminated by SIGTERM, got %v", state)
+	}
+}
+
+// TestWaitFor tests that the target is only launched once its dependencies are ready
+func TestWaitFor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses a POSIX shell")
+	}
+	tempDir := t.TempDir()
+	marker := filepath.Join(tempDir, "ready")
+	launched := filepath.Join(tempDir, "launched")
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "touch "+launched)
+	}
+
+	// The command condition succeeds on its third check
+	var checks []string
+	originalExecCommandContext := execCommandContext
+	defer func() { execCommandContext = originalExecCommandContext }()
+	execCommandContext = func(ctx context.Context, command string, args ...string) *exec.Cmd {
+		checks = append(checks, strings.Join(append([]string{command}, args...), "|"))
+		if command == "db-ready" && len(checks) >= 3 {
+			return exec.CommandContext(ctx, "sh", "-c", "exit 0")
+		}
+		return exec.CommandContext(ctx, "sh", "-c", "exit 1")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer listener.Close()
+
+	// The file only appears after a while
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		_ = os.WriteFile(marker, nil, 0644)
+	}()
+	config := &Configuration{Target: "sh", WaitFor: []WaitCondition{
+		{File: marker, Timeout: 5 * time.Second, Interval: 50 * time.Millisecond},
+		{TCP: listener.Addr().String(), Timeout: time.Second, Interval: 50 * time.Millisecond},
+		{Command: `db-ready --host "db 1"`, Timeout: time.Second, Interval: 50 * time.Millisecond},
+	}}
+	start := time.Now()
+	if err := NewLauncher(config).Launch(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Expected launch to wait for the file, took %v", elapsed)
+	}
+	if _, err := os.Stat(launched); err != nil {
+		t.Errorf("Expected target to be launched, got: %v", err)
+	}
+	if len(checks) != 3 || checks[0] != "db-ready|--host|db 1" {
+		t.Errorf("Expected command to be checked 3 times, got: %q", checks)
+	}
+
+	// Conditions that never become ready are all reported, and the target isn't launched
+	os.Remove(launched)
+	config.WaitFor = []WaitCondition{
+		{File: marker, Timeout: time.Second, Interval: 50 * time.Millisecond},
+		{Unix: filepath.Join(tempDir, "missing.sock"), Timeout: 200 * time.Millisecond, Interval: 50 * time.Millisecond},
+		{Command: "db-down", Timeout: 200 * time.Millisecond, Interval: 50 * time.Millisecond},
+	}
+	err = NewLauncher(config).Launch()
+	if err == nil || !strings.Contains(err.Error(), "unix socket") || !strings.Contains(err.Error(), `command "db-down" not ready`) ||
+		strings.Contains(err.Error(), "file "+marker) {
+		t.Errorf("Expected unix socket and command to be reported, got: %v", err)
+	}
+	if _, err := os.Stat(launched); err == nil {
+		t.Errorf("Expected target not to be launched")
+	}
+}
+
+// TestRetries tests that the target is rerun while it fails with a retried exit code
+func TestRetries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses a POSIX shell")
+	}
+	tempDir := t.TempDir()
+	t.Setenv(runtimeDirEnvVar, tempDir)
+	attemptsLog := filepath.Join(tempDir, "attempts")
+
+	// Each attempt logs the input it read, and the third one succeeds
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", `read line; echo "$line"; echo "$line" >> "$0"; [ $(wc -l < "$0") -ge 3 ] || exit ${1:-75}`, attemptsLog, args[0])
+	}
+	inputPath := filepath.Join(tempDir, "input")
+	if err := os.WriteFile(inputPath, []byte("skipped\ninput\n"), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		exitCode      string
+		retries       int
+		pipe          bool
+		expectedCode  int
+		expectedTries int
+	}{
+		{name: "Succeeds on third attempt", exitCode: "75", retries: 3, expectedCode: 0, expectedTries: 3},
+		{name: "Out of retries", exitCode: "75", retries: 1, expectedCode: 75, expectedTries: 2},
+		{name: "Exit code not retried", exitCode: "1", retries: 3, expectedCode: 1, expectedTries: 1},
+		{name: "Consumed pipe", exitCode: "75", retries: 3, pipe: true, expectedCode: 75, expectedTries: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Remove(attemptsLog)
+			// The output log is truncated once, before the first attempt
+			stdoutLog := filepath.Join(t.TempDir(), "stdout.log")
+			os.WriteFile(stdoutLog, []byte("earlier run\n"), 0644)
+			config := &Configuration{Target: "sh", StdoutLog: stdoutLog, OutputLogMode: "truncate", Retry: RetryConfig{
+				ExitCodes: []int{75}, Retries: tc.retries, Delay: 10 * time.Millisecond, Backoff: 2, MaxDelay: time.Second,
+			}}
+			launcher := NewLauncher(config)
+			launcher.Args = []string{tc.exitCode}
+
+			// Retries start reading where the first attempt did
+			input, _ := os.Open(inputPath)
+			defer input.Close()
+			_, _ = input.Seek(int64(len("skipped\n")), io.SeekStart)
+			launcher.Stdin = input
+			if tc.pipe {
+				reader, writer, _ := os.Pipe()
+				defer reader.Close()
+				go func() {
+					fmt.Fprintln(writer, "input")
+					writer.Close()
+				}()
+				launcher.Stdin = reader
+			}
+
+			err := launcher.Launch()
+			if code := exitCodeOf(err); code != tc.expectedCode {
+				t.Errorf("Expected exit code %d, got %d (%v)", tc.expectedCode, code, err)
+			}
+			attempts, _ := os.ReadFile(attemptsLog)
+			if expected := strings.Repeat("input\n", tc.expectedTries); string(attempts) != expected {
+				t.Errorf("Expected attempts %q, got %q", expected, attempts)
+			}
+			output, _ := os.ReadFile(stdoutLog)
+			if expected := strings.Repeat("input\n", tc.expectedTries); string(output) != expected {
+				t.Errorf("Expected output log %q, got %q", expected, output)
+			}
+		})
+	}
+}
+
+// TestLaunchHooks tests that pre-launch and post-exit hooks run around the target
+func TestLaunchHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses a POSIX shell")
+	}
+
+	file := writeTempConfig(t, `
+target = "app"
+preLaunch.1 = setup "first step"
+preLaunch.2 = failing-setup
+postExit.1 = cleanup
+`)
+	config, err := parseConfig(file)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(config.PreLaunch) != 2 || config.PreLaunch[0] != `setup "first step"` || len(config.PostExit) != 1 {
+		t.Fatalf("Unexpected hooks: %q / %q", config.PreLaunch, config.PostExit)
+	}
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+
+	// Record every executed command name; the target exits with code 7 and the failing hook with 1
+	tempDir := t.TempDir()
+	recordPath := filepath.Join(tempDir, "record.txt")
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		script := fmt.Sprintf(`echo "$0 code=$%s" >> %q`, exitCodeEnvVar, recordPath)
+		switch command {
+		case "app":
+			script += "; exit 7"
+		case "failing-setup":
+			script += "; exit 1"
+		}
+		return exec.Command("sh", append([]string{"-c", script, command}, args...)...)
+	}
+
+	tests := []struct {
+		policy      string
+		expectError string
+		expected    string
+	}{
+		{"", "pre-launch hook \"failing-setup\" failed", "setup code=\nfailing-setup code=\n"},
+		{"warn", "exit status 7", "setup code=\nfailing-setup code=\napp code=\ncleanup code=7\n"},
+		{"ignore", "exit status 7", "setup code=\nfailing-setup code=\napp code=\ncleanup code=7\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run("Policy "+tc.policy, func(t *testing.T) {
+			os.Remove(recordPath)
+			config.PreLaunchFailure = tc.policy
+			err := NewLauncher(config).Launch()
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Errorf("Expected error containing %q, got: %v", tc.expectError, err)
+			}
+			record, _ := os.ReadFile(recordPath)
+			if string(record) != tc.expected {
+				t.Errorf("Expected commands %q, got %q", tc.expected, record)
+			}
+		})
+	}
+}
+
+// TestRouting tests parsing of routing rules and the selection of the launched target
+func TestRouting(t *testing.T) {
+	file := writeTempConfig(t, `
+target = "cli.exe"
+extraArgs = --quiet
+extraArgsOrder = before
+route.2.firstArg = help
+route.2.argCount = 1
+route.2.target = help.exe
+route.1.anyArg = --gui*
+route.1.target = gui.exe
+route.1.extraArgs = --windowed
+route.1.extraArgsOrder = after
+route.3.argCount = 3+
+route.3.extraArgs = --batch
+route.4.anyArg = --config=*
+route.4.target = configured.exe
+route.5.firstArg = *.tx[!a-s]
+route.5.target = text.exe
+`)
+	config, err := parseConfig(file)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(config.Routes) != 5 || config.Routes[0].Index != 1 || config.Routes[2].Target != "cli.exe" {
+		t.Fatalf("Unexpected routes: %+v", config.Routes)
+	}
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	var launched []string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		launched = append([]string{command}, args...)
+		return successCommand()
+	}
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"file", "--gui=full"}, []string{"gui.exe", "file", "--gui=full", "--windowed"}},
+		{[]string{"help"}, []string{"help.exe", "help"}},
+		{[]string{"help", "me"}, []string{"cli.exe", "--quiet", "help", "me"}},
+		{[]string{"a", "b", "c"}, []string{"cli.exe", "--batch", "a", "b", "c"}},
+		{nil, []string{"cli.exe", "--quiet"}},
+		{[]string{"--config=/etc/x"}, []string{"configured.exe", "--config=/etc/x"}},
+		{[]string{"dir/a.txt"}, []string{"text.exe", "dir/a.txt"}},
+		{[]string{"https://host/a.txt"}, []string{"text.exe", "https://host/a.txt"}},
+		{[]string{"dir/a.txa"}, []string{"cli.exe", "--quiet", "dir/a.txa"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			launcher := NewLauncher(config)
+			launcher.Args = tc.args
+			if err := launcher.Launch(); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if strings.Join(launched, "|") != strings.Join(tc.expected, "|") {
+				t.Errorf("Expected command %q, got %q", tc.expected, launched)
+			}
+		})
+	}
+
+	// Invalid rules
+	for _, content := range []string{
+		"target = a.exe\nroute.1.argCount = many",
+		"target = a.exe\nroute.1.argCount = 3-1",
+		"target = a.exe\nroute.1.firstArg = [",
+		"target = a.exe\nroute.1.anyArg = a\\",
+		"target = a.exe\nroute.1.colour = red",
+		"target = a.exe\nroute.1.extraArgs = --x",
+	} {
+		if _, err := parseConfig(writeTempConfig(t, content)); err == nil {
+			t.Errorf("Expected error for %q, got nil", content)
+		}
+	}
+}
+
+// TestParseLauncherFlags tests that only the launcher's own leading flags are taken from the
+// command line, and the target's flags are routed and passed on
+func TestParseLauncherFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected launcherFlags
+		rest     []string
+	}{
+		{nil, launcherFlags{}, []string{}},
+		{[]string{"-config", "a.cfg", "--gui", "-v"}, launcherFlags{configPath: "a.cfg"}, []string{"--gui", "-v"}},
+		{[]string{"--config=a.cfg", "--proxylauncher-ps"}, launcherFlags{configPath: "a.cfg", ps: true}, []string{}},
+		{[]string{"--proxylauncher-stop", "app", "x"}, launcherFlags{stop: "app"}, []string{"x"}},
+		{[]string{"file", "-config", "a.cfg"}, launcherFlags{}, []string{"file", "-config", "a.cfg"}},
+		{[]string{"--", "-config", "a.cfg"}, launcherFlags{}, []string{"-config", "a.cfg"}},
+		{[]string{"--gui", "-config", "a.cfg"}, launcherFlags{}, []string{"--gui", "-config", "a.cfg"}},
+	}
+	for _, tc := range tests {
+		flags, rest, err := parseLauncherFlags(tc.args)
+		if err != nil {
+			t.Errorf("Expected no error for %q, got: %v", tc.args, err)
+		} else if flags != tc.expected || strings.Join(rest, "|") != strings.Join(tc.rest, "|") {
+			t.Errorf("For %q expected %+v %q, got %+v %q", tc.args, tc.expected, tc.rest, flags, rest)
+		}
+	}
+	for _, args := range [][]string{{"-config"}, {"--proxylauncher-ps=maybe"}} {
+		if _, _, err := parseLauncherFlags(args); err == nil {
+			t.Errorf("Expected error for %q, got nil", args)
+		}
+	}
+
+	// A route matches the target's flag once the launcher's flags are taken off
+	config, err := parseConfig(writeTempConfig(t, "target = cli.exe\nroute.1.anyArg = --gui\nroute.1.target = gui.exe\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	_, rest, _ := parseLauncherFlags([]string{"-config", "tool.cfg", "--gui"})
+	if route := config.selectRoute(rest); route == nil || route.Target != "gui.exe" {
+		t.Errorf("Expected --gui to be routed to gui.exe, got %+v", route)
+	}
+}
+
+// TestResolveTarget tests fallback target lists and version-aware glob matching
+func TestResolveTarget(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, version := range []string{"1.2", "1.9", "1.10", "2.0-beta"} {
+		binDir := filepath.Join(tempDir, "tool-"+version, "bin")
+		os.MkdirAll(binDir, 0755)
+		if version != "2.0-beta" {
+			os.WriteFile(filepath.Join(binDir, "tool"), []byte("dummy executable"), 0755)
+		}
+	}
+	fallback := filepath.Join(tempDir, "fallback")
+	os.WriteFile(fallback, []byte("dummy executable"), 0755)
+	missing := filepath.Join(tempDir, "missing")
+	pattern := filepath.Join(tempDir, "tool-*", "bin", "tool")
+	noMatch := filepath.Join(tempDir, "other-*", "bin", "tool")
+
+	tests := []struct {
+		name        string
+		target      string
+		expected    string
+		errorSubstr string
+	}{
+		{"Single", fallback, fallback, ""},
+		{"Newest Glob Match", pattern, filepath.Join(tempDir, "tool-1.10", "bin", "tool"), ""},
+		{"Fallback Chain", missing + " | " + noMatch + " | " + fallback, fallback, ""},
+		{"Nothing Found", missing + "|" + noMatch, "", "tried: " + missing + ", " + noMatch + " (no matches)"},
+		{"Single Missing", missing, "", "target executable not found: " + missing},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := resolveTarget(tc.target)
+			if tc.errorSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errorSubstr) {
+					t.Errorf("Expected error containing %q, got: %v", tc.errorSubstr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+// TestCompareVersions tests version-aware string ordering
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"tool-1.10", "tool-1.9", 1},
+		{"tool-1.2", "tool-1.10", -1},
+		{"tool-2", "tool-2", 0},
+		{"tool-2.0", "tool-2", 1},
+		{"tool-a", "tool-b", -1},
+		{"tool-1.2", "tool-1.2-rc1", 1},
+		{"tool-1.2-rc1", "tool-1.2", -1},
+		{"tool-1.2.exe", "tool-1.2-rc1.exe", 1},
+		{"tool-1.2-rc1.exe", "tool-1.2.exe", -1},
+		{"tool-1.2-rc1", "tool-1.2-rc2", -1},
+		{"tool-1.2-beta", "tool-1.2-rc1", -1},
+		{"tool-1.2-rc1", "tool-1.1", 1},
+		{"tool-1.2~beta", "tool-1.2", -1},
+	}
+
+	for _, tc := range tests {
+		if result := compareVersions(tc.a, tc.b); result != tc.expected {
+			t.Errorf("compareVersions(%q, %q): expected %d, got %d", tc.a, tc.b, tc.expected, result)
+		}
+	}
+}
+
+// TestTargetChecksum tests pinning the target checksum and its verification in loadConfig
+func TestTargetChecksum(t *testing.T) {
+	tempDir := t.TempDir()
+	target := filepath.Join(tempDir, "app.exe")
+	os.WriteFile(target, []byte("dummy executable"), 0755)
+	configPath := filepath.Join(tempDir, "proxylauncher.cfg")
+	os.WriteFile(configPath, []byte("# Pinned app\ntarget="+target+"\ntargetSha256=\n"), 0644)
+
+	// An empty digest means the target isn't pinned
+	if _, err := loadConfig(configPath); err != nil {
+		t.Errorf("Expected unpinned config to load, got: %v", err)
+	}
+
+	summary, err := pinTargetChecksums(configPath)
+	if err != nil {
+		t.Fatalf("Expected no error pinning, got: %v", err)
+	}
+	expected, _ := fileSha256(target)
+	if !strings.Contains(summary, expected) {
+		t.Errorf("Expected summary to contain %s, got %q", expected, summary)
+	}
+	content, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(content), "# Pinned app\n") || strings.Count(string(content), "targetSha256=") != 1 ||
+		!strings.Contains(string(content), "targetSha256="+expected+"\n") {
+		t.Errorf("Unexpected config after pinning: %q", content)
+	}
+
+	if _, err := loadConfig(configPath); err != nil {
+		t.Errorf("Expected pinned config to load, got: %v", err)
+	}
+
+	// A changed target must be refused, naming both digests
+	os.WriteFile(target, []byte("tampered executable"), 0755)
+	actual, _ := fileSha256(target)
+	_, err = loadConfig(configPath)
+	if err == nil || !strings.Contains(err.Error(), "expected sha256 "+expected+", got "+actual) {
+		t.Errorf("Expected checksum mismatch error, got: %v", err)
+	}
+
+	if _, err := parseSha256("targetSha256", "abc123"); err == nil {
+		t.Errorf("Expected error for malformed digest, got nil")
+	}
+
+	// Pinning keeps the file's permissions and Windows line endings
+	os.WriteFile(configPath, []byte("# Pinned app\r\ntarget="+target+"\r\n"), 0600)
+	os.Chmod(configPath, 0600)
+	if _, err := pinTargetChecksums(configPath); err != nil {
+		t.Fatalf("Expected no error pinning, got: %v", err)
+	}
+	content, _ = os.ReadFile(configPath)
+	if expected := "# Pinned app\r\ntarget=" + target + "\r\ntargetSha256=" + actual + "\r\n"; string(content) != expected {
+		t.Errorf("Expected config %q after pinning, got %q", expected, content)
+	}
+	if info, _ := os.Stat(configPath); runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600 to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+// TestSecurityPolicy tests that loadConfig refuses or warns about files other users can modify
+func TestSecurityPolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Permission checks are only performed on Unix")
+	}
+
+	newSetup := func(t *testing.T, policy string) (configPath, target string) {
+		tempDir := t.TempDir()
+		target = filepath.Join(tempDir, "app")
+		os.WriteFile(target, []byte("dummy executable"), 0755)
+		configPath = filepath.Join(tempDir, "proxylauncher.cfg")
+		content := "target=" + target + "\n"
+		if policy != "" {
+			content += "securityPolicy=" + policy + "\n"
+		}
+		os.WriteFile(configPath, []byte(content), 0644)
+		return configPath, target
+	}
+
+	t.Run("Secure", func(t *testing.T) {
+		configPath, _ := newSetup(t, "refuse")
+		if _, err := loadConfig(configPath); err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+	})
+
+	t.Run("World-writable Config", func(t *testing.T) {
+		configPath, _ := newSetup(t, "refuse")
+		os.Chmod(configPath, 0666)
+		_, err := loadConfig(configPath)
+		if err == nil || !strings.Contains(err.Error(), "config file "+configPath+" is world-writable") {
+			t.Errorf("Expected world-writable config error, got: %v", err)
+		}
+	})
+
+	t.Run("World-writable Directory", func(t *testing.T) {
+		configPath, _ := newSetup(t, "")
+		os.Chmod(filepath.Dir(configPath), 0777)
+		_, err := loadConfig(configPath)
+		if err == nil || !strings.Contains(err.Error(), "config directory") {
+			t.Errorf("Expected world-writable directory error, got: %v", err)
+		}
+
+		// A sticky directory like /tmp is fine
+		os.Chmod(filepath.Dir(configPath), 0777|os.ModeSticky)
+		if _, err := loadConfig(configPath); err != nil {
+			t.Errorf("Expected sticky directory to be accepted, got: %v", err)
+		}
+	})
+
+	t.Run("Foreign Target", func(t *testing.T) {
+		if os.Getuid() != 0 {
+			t.Skip("Changing file ownership requires root")
+		}
+		configPath, target := newSetup(t, "refuse")
+		os.Chown(target, 12345, 12345)
+		_, err := loadConfig(configPath)
+		if err == nil || !strings.Contains(err.Error(), "target "+target+" is owned by uid 12345") {
+			t.Errorf("Expected foreign owner error, got: %v", err)
+		}
+	})
+
+	t.Run("Warn Policy", func(t *testing.T) {
+		configPath, target := newSetup(t, "warn")
+		os.Chmod(target, 0777)
+
+		var logBuf bytes.Buffer
+		launcherLog.SetOutput(&logBuf)
+		defer launcherLog.SetOutput(os.Stderr)
+
+		if _, err := loadConfig(configPath); err != nil {
+			t.Errorf("Expected no error with warn policy, got: %v", err)
+		}
+		if !strings.Contains(logBuf.String(), "target "+target+" is world-writable") {
+			t.Errorf("Expected warning to be logged, got %q", logBuf.String())
+		}
+	})
+}
+
+// TestPreflightTarget tests the checks that the target can actually be executed
+func TestPreflightTarget(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses Unix permissions and scripts")
+	}
+	tempDir := t.TempDir()
+	writeTarget := func(name string, content []byte, perm os.FileMode) string {
+		path := filepath.Join(tempDir, name)
+		os.WriteFile(path, content, perm)
+		return path
+	}
+
+	type preflightCase struct {
+		name        string
+		path        string
+		errorSubstr string
+	}
+	tests := []preflightCase{
+		{"Valid Script", writeTarget("valid.sh", []byte("#!/bin/sh\necho hi\n"), 0755), ""},
+		{"Missing File", filepath.Join(tempDir, "missing"), ""},
+		{"Not Executable", writeTarget("plain.sh", []byte("#!/bin/sh\n"), 0644), "is not executable"},
+		{"Missing Interpreter", writeTarget("broken.sh", []byte("#!/no/such/shell -e\n"), 0755), "interpreter /no/such/shell of target script"},
+		{"Missing Env Program", writeTarget("env.sh", []byte("#!/usr/bin/env no-such-program-xyz\n"), 0755), "no-such-program-xyz of target script"},
+		{"Windows Executable", writeTarget("app.exe", []byte("MZ\x90\x00"), 0755), "is a Windows executable"},
+	}
+
+	if runtime.GOOS == "linux" {
+		// Take a native binary and patch its machine type to another architecture
+		if native, err := os.ReadFile("/bin/true"); err == nil {
+			foreign := slices.Clone(native)
+			machine := elf.EM_S390
+			if runtime.GOARCH == "s390x" {
+				machine = elf.EM_X86_64
+			}
+			if foreign[elf.EI_DATA] == byte(elf.ELFDATA2MSB) {
+				binary.BigEndian.PutUint16(foreign[18:], uint16(machine))
+			} else {
+				binary.LittleEndian.PutUint16(foreign[18:], uint16(machine))
+			}
+			tests = append(tests,
+				preflightCase{"Native Binary", writeTarget("native", native, 0755), ""},
+				preflightCase{"Foreign Binary", writeTarget("foreign", foreign, 0755), "is built for architecture " + machine.String()},
+			)
+		}
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := preflightTarget(tc.path, nil)
+			if tc.errorSubstr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.errorSubstr) {
+				t.Errorf("Expected error containing %q, got: %v", tc.errorSubstr, err)
+			}
+		})
+	}
+}
+
+// TestInterpreters tests running script targets through built-in and configured interpreters
+func TestInterpreters(t *testing.T) {
+	file := writeTempConfig(t, `
+target = "tool.jar"
+extraArgs = --fast
+extraArgsOrder = before
+interpreter..jar = "/opt/java/bin/java -Xmx1g -jar"
+interpreter..JS =
+`)
+	config, err := parseConfig(file)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	tests := []struct {
+		target   string
+		expected []string
+	}{
+		{"tool.jar", []string{"/opt/java/bin/java", "-Xmx1g", "-jar"}},
+		{"script.PY", parseArgs(defaultInterpreters()[".py"])},
+		{"script.js", nil}, // disabled by the empty override
+		{"app", nil},
+	}
+	for _, tc := range tests {
+		if result := config.interpreterFor(tc.target); !slices.Equal(result, tc.expected) {
+			t.Errorf("Expected interpreter %q for %s, got %q", tc.expected, tc.target, result)
+		}
+	}
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	var launched []string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		launched = append([]string{command}, args...)
+		return successCommand()
+	}
+
+	launcher := NewLauncher(config)
+	launcher.Args = []string{"input.txt"}
+	if err := launcher.Launch(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []string{"/opt/java/bin/java", "-Xmx1g", "-jar", "tool.jar", "--fast", "input.txt"}
+	if !slices.Equal(launched, expected) {
+		t.Errorf("Expected command %q, got %q", expected, launched)
+	}
+
+	// Scripts run through an interpreter don't need to be executable, but the interpreter must exist
+	script := filepath.Join(t.TempDir(), "tool.jar")
+	os.WriteFile(script, []byte("not executable"), 0644)
+	err = preflightTarget(script, []string{"no-such-interpreter-xyz"})
+	if err == nil || !strings.Contains(err.Error(), "interpreter no-such-interpreter-xyz for target") {
+		t.Errorf("Expected missing interpreter error, got: %v", err)
+	}
+	if _, err := exec.LookPath("go"); err == nil {
+		if err := preflightTarget(script, []string{"go"}); err != nil {
+			t.Errorf("Expected no error for existing interpreter, got: %v", err)
+		}
+	}
+
+	// Executable scripts with a "#!" line keep their own interpreter, unless one is configured
+	if runtime.GOOS != "windows" {
+		tempDir := t.TempDir()
+		executable := filepath.Join(tempDir, "tool.py")
+		os.WriteFile(executable, []byte("#!/opt/venv/bin/python\n"), 0755)
+		if result := config.interpreterFor(executable); result != nil {
+			t.Errorf("Expected executable script to run directly, got %q", result)
+		}
+		withoutShebang := filepath.Join(tempDir, "other.py")
+		os.WriteFile(withoutShebang, []byte("print('hi')\n"), 0755)
+		if result := config.interpreterFor(withoutShebang); !slices.Equal(result, parseArgs(defaultInterpreters()[".py"])) {
+			t.Errorf("Expected built-in interpreter for script without #! line, got %q", result)
+		}
+		jar := filepath.Join(tempDir, "tool.jar")
+		os.WriteFile(jar, []byte("#!/bin/sh\n"), 0755)
+		if result := config.interpreterFor(jar); result == nil {
+			t.Errorf("Expected configured interpreter to apply to executable script")
+		}
+	}
+
+	if _, err := parseConfig(writeTempConfig(t, "target = a\ninterpreter.py = python")); err == nil {
+		t.Errorf("Expected error for interpreter key without extension dot, got nil")
+	}
+}
+
+// TestParseProcessLimits tests parsing of resource limit, nice and umask settings
+func TestParseProcessLimits(t *testing.T) {
+	config, err := parseConfig(writeTempConfig(t, `
+target = app
+limit.NOFILE = 1024
+limit.as = 2G:unlimited
+limit.cpu = 90
+nice = -5
+umask = 077
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if config.Limits["nofile"] != (ResourceLimit{1024, 1024}) || config.Limits["as"] != (ResourceLimit{2 << 30, rlimitInfinity}) ||
+		config.Limits["cpu"] != (ResourceLimit{90, 90}) {
+		t.Errorf("Unexpected limits: %+v", config.Limits)
+	}
+	if config.Nice == nil || *config.Nice != -5 || config.Umask == nil || *config.Umask != 0077 {
+		t.Errorf("Unexpected nice/umask: %v/%v", config.Nice, config.Umask)
+	}
+
+	for _, content := range []string{
+		"limit.stack = 8M",
+		"limit.nofile = many",
+		"limit.nofile = 512:256",
+		"nice = 20",
+		"umask = 999",
+	} {
+		if _, err := parseConfig(writeTempConfig(t, "target = app\n"+content)); err == nil {
+			t.Errorf("Expected error for %q, got nil", content)
+		}
+	}
+}
+
+// TestParseSchedulingSettings tests parsing of CPU affinity, I/O priority and OOM score settings
+func TestParseSchedulingSettings(t *testing.T) {
+	config, err := parseConfig(writeTempConfig(t, `
+target = app
+cpuAffinity = 0-2, 5
+ioClass = Idle
+oomScoreAdj = -100
+descendants = Kill
+reportUsage = Stderr
+idleTimeout = 1m30s
+maxRSS = 512M
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !slices.Equal(config.CPUAffinity.cpus(), []int{0, 1, 2, 5}) || config.IOClass != "idle" ||
+		config.OOMScoreAdj == nil || *config.OOMScoreAdj != -100 {
+		t.Errorf("Unexpected settings: %v/%q/%v", config.CPUAffinity.cpus(), config.IOClass, config.OOMScoreAdj)
+	}
+	if config.IdleTimeout != 90*time.Second || config.MaxRSS != 512<<20 {
+		t.Errorf("Unexpected watchdog settings: %v/%d", config.IdleTimeout, config.MaxRSS)
+	}
+	if config.Descendants != "kill" || config.ReportUsage != "stderr" {
+		t.Errorf("Unexpected descendants policy %q or usage report %q", config.Descendants, config.ReportUsage)
+	}
+
+	for _, content := range []string{
+		"cpuAffinity = 3-1",
+		"cpuAffinity = all",
+		"cpuAffinity = 0-2000000000",
+		"cpuAffinity = 1024",
+		"cpuAffinity = -1",
+		"ioClass = urgent",
+		"ioLevel = 3",
+		"ioClass = realtime\nioLevel = 8",
+		"oomScoreAdj = 1001",
+		"descendants = orphan",
+		"reportUsage = always",
+		"idleTimeout = -5",
+		"idleTimeout = soon",
+		"idleTimeout = inf",
+		"idleTimeout = NaN",
+		"idleTimeout = 1e12",
+		"idleTimeout = 1e400",
+		"idleTimeout = 9999999h",
+		"waitFor.1.file = /tmp/x\nwaitFor.1.timeout = 1e12",
+		"retryOnExitCodes = 75\nretries = 2\nretryDelay = +Inf",
+	} {
+		if _, err := parseConfig(writeTempConfig(t, "target = app\n"+content)); err == nil {
+			t.Errorf("Expected error for %q, got nil", content)
+		}
+	}
+}
+
+// TestRunAsUser tests that the target runs with the configured credentials and environment
+func TestRunAsUser(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Credentials are only supported on Unix")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("User nobody not found")
+	}
+
+	if os.Geteuid() != 0 {
+		config := &Configuration{Target: "sh", RunAsUser: "nobody"}
+		err := applyCredentials(exec.Command("sh"), config)
+		if err == nil || !strings.Contains(err.Error(), "requires root privileges") {
+			t.Errorf("Expected missing privileges error, got: %v", err)
+		}
+		return
+	}
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", `echo "$(id -u) $(id -g) $(id -G) $HOME $USER $LOGNAME"`)
+	}
+
+	logPath := filepath.Join(t.TempDir(), "stdout.log")
+	launcher := NewLauncher(&Configuration{
+		Target:              "sh",
+		RunAsUser:           "nobody",
+		RunAsGroup:          nobody.Gid,
+		SupplementaryGroups: []string{},
+		StdoutLog:           logPath,
+	})
+	if err := launcher.Launch(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content, _ := os.ReadFile(logPath)
+	expected := fmt.Sprintf("%s %s %s %s nobody nobody\n", nobody.Uid, nobody.Gid, nobody.Gid, nobody.HomeDir)
+	if string(content) != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+
+	if err := applyCredentials(exec.Command("sh"), &Configuration{RunAsUser: "no-such-user-xyz"}); err == nil {
+		t.Errorf("Expected error for unknown user, got nil")
+	}
+}
+
+// TestParseSandbox tests parsing of the sandbox settings
+func TestParseSandbox(t *testing.T) {
+	config, err := parseConfig(writeTempConfig(t, `
+target = app
+sandbox.noNetwork = yes
+sandbox.privateTmp = on
+sandbox.readOnly.2 = /srv/data
+sandbox.readOnly.1 = /etc
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !config.Sandbox.NoNetwork || !config.Sandbox.PrivateTmp || !slices.Equal(config.Sandbox.ReadOnly, []string{"/etc", "/srv/data"}) {
+		t.Errorf("Unexpected sandbox settings: %+v", config.Sandbox)
+	}
+	if !config.Sandbox.enabled() || (&SandboxConfig{}).enabled() {
+		t.Errorf("Unexpected sandbox enabled state")
+	}
+}
+
+// TestParseDetach tests parsing of the detach settings and their conflicts with foreground-only settings
+func TestParseDetach(t *testing.T) {
+	config, err := parseConfig(writeTempConfig(t, "target = app\ndetach = yes\npidFile = /run/app.pid\nstdoutLog = app.log\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !config.Detach || config.PidFile != "/run/app.pid" {
+		t.Errorf("Unexpected detach settings: %v/%q", config.Detach, config.PidFile)
+	}
+
+	for content, expected := range map[string]string{
+		"pidFile = app.pid":                                "pidFile requires detach",
+		"detach = yes\npty = yes":                          "pty can't be used with detach",
+		"detach = yes\npostExit.1 = cleanup":               "postExit can't be used with detach",
+		"detach = yes\nidleTimeout = 10":                   "idleTimeout can't be used with detach",
+		"detach = yes\ncombinedLog = a.log\nstderrLog = b": "combinedLog can't be used",
+	} {
+		_, err := parseConfig(writeTempConfig(t, "target = app\n"+content))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q for %q, got: %v", expected, content, err)
+		}
+	}
+}
+
+// TestParseListen tests parsing of the listener mode settings
+func TestParseListen(t *testing.T) {
+	config, err := parseConfig(writeTempConfig(t, "target = app\nlisten = unix:/run/app.sock\nmaxConnections = 4\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if config.Listen != "unix:/run/app.sock" || config.MaxConnections != 4 {
+		t.Errorf("Unexpected listen settings: %q/%d", config.Listen, config.MaxConnections)
+	}
+
+	for content, expected := range map[string]string{
+		"listen = 127.0.0.1:8080":                                         "must be 'tcp:<host>:<port>' or 'unix:<path>'",
+		"listen = tcp:8080":                                               "invalid listen value",
+		"listen = unix:":                                                  "invalid listen value",
+		"listen = tcp::8080\nmaxConnections = -1":                         "invalid maxConnections value",
+		"maxConnections = 2":                                              "maxConnections requires listen",
+		"listen = tcp::8080\ndetach = yes":                                "detach can't be used with listen",
+		"listen = tcp::8080\ndescendants = kill":                          "descendants can't be used with listen",
+		"listen = tcp::8080\npidFile = /run/a.pid":                        "pidFile can't be used with listen",
+		"listen = tcp::8080\nstdoutLog = a.log\noutputLogMode = truncate": "outputLogMode=truncate can't be used with listen",
+		"listen = tcp::8080\nstdoutLog = a.log\noutputLogMaxSize = 1M":    "outputLogMaxSize can't be used with listen",
+	} {
+		_, err := parseConfig(writeTempConfig(t, "target = app\n"+content))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q for %q, got: %v", expected, content, err)
+		}
+	}
+}
+
+// TestParseWaitFor tests parsing of the dependencies waited for before launching
+func TestParseWaitFor(t *testing.T) {
+	config, err := parseConfig(writeTempConfig(t, "target = app\nwaitFor.2.command = pg_isready\nwaitFor.1.tcp = db:5432\nwaitFor.1.timeout = 1m\nwaitFor.1.interval = 0.5\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []WaitCondition{
+		{Index: 1, TCP: "db:5432", Timeout: time.Minute, Interval: 500 * time.Millisecond},
+		{Index: 2, Command: "pg_isready", Timeout: defaultWaitTimeout, Interval: defaultWaitInterval},
+	}
+	if !slices.Equal(config.WaitFor, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, config.WaitFor)
+	}
+
+	for content, expected := range map[string]string{
+		"waitFor.1.timeout = 10":                        "waitFor 1 must set exactly one of",
+		"waitFor.1.file = a\nwaitFor.1.unix = b":        "waitFor 1 must set exactly one of",
+		"waitFor.1.tcp = db":                            "invalid waitFor.1.tcp value",
+		"waitFor.1.file = a\nwaitFor.1.interval = soon": "invalid waitFor.1.interval value",
+		"waitFor.1.port = 80":                           "unknown waitFor setting",
+	} {
+		_, err := parseConfig(writeTempConfig(t, "target = app\n"+content))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q for %q, got: %v", expected, content, err)
+		}
+	}
+}
+
+// TestParseRetry tests parsing of the retry settings
+func TestParseRetry(t *testing.T) {
+	config, err := parseConfig(writeTempConfig(t, "target = app\nretryOnExitCodes = 75, 111\nretries = 3\nretryDelay = 500ms\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := RetryConfig{ExitCodes: []int{75, 111}, Retries: 3, Delay: 500 * time.Millisecond,
+		Backoff: defaultRetryBackoff, MaxDelay: defaultRetryMaxDelay}
+	if !slices.Equal(config.Retry.ExitCodes, expected.ExitCodes) || config.Retry.Retries != expected.Retries ||
+		config.Retry.Delay != expected.Delay || config.Retry.Backoff != expected.Backoff || config.Retry.MaxDelay != expected.MaxDelay {
+		t.Errorf("Expected %+v, got %+v", expected, config.Retry)
+	}
+
+	for content, expected := range map[string]string{
+		"retryOnExitCodes = 0":  "invalid retryOnExitCodes value",
+		"retryOnExitCodes = 75": "retries must be specified",
+		"retries = 2":           "retryOnExitCodes must be specified",
+		"retryOnExitCodes = 75\nretries = 2\nretryBackoff = 0.5": "invalid retryBackoff value",
+		"retryOnExitCodes = 75\nretries = 2\ndetach = yes":       "retries can't be used with detach",
+	} {
+		_, err := parseConfig(writeTempConfig(t, "target = app\n"+content))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q for %q, got: %v", expected, content, err)
+		}
+	}
+}
+
+// TestParseFdList tests parsing of the file descriptors passed on to the target
+func TestParseFdList(t *testing.T) {
+	config, err := parseConfig(writeTempConfig(t, "target = app\npassFds = 3, 7\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !slices.Equal(config.PassFds, []int{3, 7}) {
+		t.Errorf("Expected passFds [3 7], got %v", config.PassFds)
+	}
+
+	for _, value := range []string{"2", "3,x", ""} {
+		if _, err := parseFdList("passFds", value); err == nil {
+			t.Errorf("Expected error for %q, got nil", value)
+		}
+	}
+}
+
+// TestParseFsAllow tests parsing of the Landlock filesystem restriction settings
+func TestParseFsAllow(t *testing.T) {
+	config, err := parseConfig(writeTempConfig(t, `
+target = app
+fsAllow.readOnly.1 = /usr
+fsAllow.readOnly.0 = /etc
+fsAllow.readWrite.1 = /var/cache/app
+fsAllow.unsupported = warn
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !slices.Equal(config.FsAllow.ReadOnly, []string{"/etc", "/usr"}) || !slices.Equal(config.FsAllow.ReadWrite, []string{"/var/cache/app"}) {
+		t.Errorf("Unexpected fsAllow paths: %+v", config.FsAllow)
+	}
+	if config.FsAllow.Unsupported != "warn" || !config.FsAllow.enabled() || (&FsAllowConfig{}).enabled() {
+		t.Errorf("Unexpected fsAllow settings: %+v", config.FsAllow)
+	}
+
+	_, err = parseConfig(writeTempConfig(t, "target = app\nfsAllow.unsupported = maybe\n"))
+	if err == nil || !strings.Contains(err.Error(), "fsAllow.unsupported") {
+		t.Errorf("Expected fsAllow.unsupported error, got: %v", err)
+	}
+}
+
+// TestMirrorTerminationSignal tests that the launcher ends by the signal that terminated the target.
+// The test binary re-runs this test as the launcher, since mirroring terminates the process.
+func TestMirrorTerminationSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Signals are only supported on Unix")
+	}
+
+	if signal := os.Getenv("PROXYLAUNCHER_TEST_SIGNAL"); signal != "" {
+		err := exec.Command("sh", "-c", "ulimit -c 0; kill -"+signal+" $$").Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			mirrorTerminationSignal(exitErr.ProcessState)
+		}
+		fmt.Println("launcher survived")
+		os.Exit(0)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		signal syscall.Signal
+	}{
+		{"SEGV", syscall.SIGSEGV},
+		{"INT", syscall.SIGINT},
+		{"TERM", syscall.SIGTERM},
+		{"PIPE", syscall.SIGPIPE},
+		{"ABRT", syscall.SIGABRT},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestMirrorTerminationSignal$")
+			cmd.Env = append(os.Environ(), "PROXYLAUNCHER_TEST_SIGNAL="+tc.name)
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			output, _ := cmd.Output()
+
+			if strings.Contains(string(output), "launcher survived") {
+				t.Fatalf("Expected launcher to terminate")
+			}
+			if !strings.Contains(stderr.String(), fmt.Sprintf("target terminated by signal %d", int(tc.signal))) {
+				t.Errorf("Expected signal to be logged, got: %q", stderr.String())
+			}
+
+			status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+			switch {
+			case status.Signaled():
+				if status.Signal() != tc.signal || status.CoreDump() {
+					t.Errorf("Expected launcher to be terminated by %v without core, got %v (core %v)", tc.signal, status.Signal(), status.CoreDump())
+				}
+			case runtime.GOOS == "linux":
+				t.Errorf("Expected launcher to be terminated by %v, got exit code %d", tc.signal, status.ExitStatus())
+			case status.ExitStatus() != 128+int(tc.signal):
+				t.Errorf("Expected exit code %d, got %d", 128+int(tc.signal), status.ExitStatus())
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// timestampFormat is the layout used when prefixing logged lines with a timestamp
+const timestampFormat = "2006-01-02 15:04:05.000 "
+
+// outputLogs holds the log files the target's output is teed into
+type outputLogs struct {
+	files []*rotatingFile
+}
+
+// openOutputLogs opens the stdout, stderr and combined log files configured in config.
+// It returns the writers to use for the target's stdout and stderr, which still pass
+// output through to the given destinations.
+func openOutputLogs(config *Configuration, stdout, stderr io.Writer) (*outputLogs, io.Writer, io.Writer, error) {
+	logs := &outputLogs{}
+	stdoutWriters := []io.Writer{stdout}
+	stderrWriters := []io.Writer{stderr}
+
+	open := func(path string) (*rotatingFile, error) {
+		file, err := openRotatingFile(path, config.OutputLogMode == "truncate", config.OutputLogMaxSize)
+		if err != nil {
+			logs.Close()
+			return nil, fmt.Errorf("error opening output log: %v", err)
+		}
+		logs.files = append(logs.files, file)
+		return file, nil
+	}
+
+	// wrap adds per-line timestamps if configured. Each stream gets its own wrapper so
+	// line boundaries are tracked separately even when both share the combined log.
+	wrap := func(w io.Writer) io.Writer {
+		if config.OutputLogTimestamps {
+			return &timestampWriter{out: w, atLineStart: true}
+		}
+		return w
+	}
+
+	if config.StdoutLog != "" {
+		file, err := open(config.StdoutLog)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		stdoutWriters = append(stdoutWriters, wrap(file))
+	}
+	if config.StderrLog != "" {
+		file, err := open(config.StderrLog)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		stderrWriters = append(stderrWriters, wrap(file))
+	}
+	if config.CombinedLog != "" {
+		// Both streams write to the same locked file, so chunks land in the order they are relayed
+		file, err := open(config.CombinedLog)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		stdoutWriters = append(stdoutWriters, wrap(file))
+		stderrWriters = append(stderrWriters, wrap(file))
+	}
+
+	return logs, teeWriter(stdoutWriters), teeWriter(stderrWriters), nil
+}
+
+// Close closes all opened log files
+func (o *outputLogs) Close() error {
+	var firstErr error
+	for _, file := range o.files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// hasOutputLogs reports whether any output log is configured
+func hasOutputLogs(config *Configuration) bool {
+	return config.StdoutLog != "" || config.StderrLog != "" || config.CombinedLog != ""
+}
+
+// teeWriter duplicates writes to several destinations. Failures of individual
+// destinations are ignored so that a missing console (e.g. for GUI-launched
+// wrappers) doesn't stop output from reaching the log files.
+type teeWriter []io.Writer
+
+func (t teeWriter) Write(p []byte) (int, error) {
+	for _, w := range t {
+		_, _ = w.Write(p)
+	}
+	return len(p), nil
+}
+
+// timestampWriter prefixes every line written through it with the current time
+type timestampWriter struct {
+	out         io.Writer
+	atLineStart bool
+}
+
+func (t *timestampWriter) Write(p []byte) (int, error) {
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if t.atLineStart {
+			buf.WriteString(time.Now().Format(timestampFormat))
+		}
+		buf.Write(line)
+		t.atLineStart = line[len(line)-1] == '\n'
+	}
+
+	if _, err := t.out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// rotatingFile is a log file that is rotated to "<path>.1" once it grows past maxSize.
+// A maxSize of 0 disables rotation. It is safe for concurrent use.
+type rotatingFile struct {
+	mu           sync.Mutex
+	path         string
+	maxSize      int64
+	file         *os.File
+	size         int64
+	rotateFailed bool // whether a failed rotation was logged already
+}
+
+// openRotatingFile opens path for appending, truncating it first if requested
+func openRotatingFile(path string, truncate bool, maxSize int64) (*rotatingFile, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if truncate {
+		flags |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &rotatingFile{path: path, maxSize: maxSize, file: file, size: info.Size()}, nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		// Output keeps going to the current file if it can't be rotated
+		if err := r.rotate(); err != nil && !r.rotateFailed {
+			logf("warning: %v", err)
+			r.rotateFailed = true
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate moves the current file to "<path>.1", replacing any previous rotation, and starts a new one.
+// The file is closed first, as Windows can't rename open files.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return r.reopen(err)
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return r.reopen(err)
+	}
+
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return r.reopen(err)
+	}
+	r.file = file
+	r.size = 0
+	return nil
+}
+
+// reopen goes on appending to the log file after rotating it failed, and returns why it failed
+func (r *rotatingFile) reopen(cause error) error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error rotating output log %s: %v, reopening it: %v", r.path, cause, err)
+	}
+	r.file = file
+	if info, err := file.Stat(); err == nil {
+		r.size = info.Size()
+	}
+	return fmt.Errorf("error rotating output log %s: %v", r.path, cause)
+}
+
+// Close closes the underlying file
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,157 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// rlimitResources maps "limit.<name>" keys to their resource numbers
+var rlimitResources = map[string]int{
+	"nofile": unix.RLIMIT_NOFILE,
+	"as":     unix.RLIMIT_AS,
+	"cpu":    unix.RLIMIT_CPU,
+	"core":   unix.RLIMIT_CORE,
+	"nproc":  unix.RLIMIT_NPROC,
+}
+
+// I/O scheduling classes and encoding, see ioprio_set(2)
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+var ioprioClasses = map[string]int{
+	"realtime":    1,
+	"best-effort": 2,
+	"idle":        3,
+}
+
+// hideTargetWindow is a no-op on Linux since hiding windows is not supported
+func hideTargetWindow(cmd *exec.Cmd) {
+	// Intentionally empty - hiding windows is only supported on Windows
+}
+
+// prepareProcessStart applies settings that the target inherits from the launcher when it
+// is started, and returns a function restoring the launcher's own settings afterwards
+func prepareProcessStart(config *Configuration) func() {
+	if config.Umask == nil {
+		return func() {}
+	}
+	// The umask is process-wide, but nothing else creates files while the target starts
+	previous := unix.Umask(*config.Umask)
+	return func() { unix.Umask(previous) }
+}
+
+// tunesStartedProcess reports whether tuneStartedProcess has settings to apply, which must
+// happen before the target gets to run
+func tunesStartedProcess(config *Configuration) bool {
+	return len(config.Limits) > 0 || config.Nice != nil || !config.CPUAffinity.isEmpty() ||
+		config.IOClass != "" || config.OOMScoreAdj != nil
+}
+
+// tuneStartedProcess applies resource limits, scheduling and OOM settings to the started
+// process, which is the sandbox init still waiting to execute the target if any are set
+func tuneStartedProcess(pid int, config *Configuration) error {
+	for _, name := range slices.Sorted(maps.Keys(config.Limits)) {
+		limit := config.Limits[name]
+		rlimit := unix.Rlimit{Cur: limit.Soft, Max: limit.Hard}
+		if err := unix.Prlimit(pid, rlimitResources[name], &rlimit, nil); err != nil {
+			return fmt.Errorf("failed to set limit.%s: %v", name, err)
+		}
+	}
+
+	if config.OOMScoreAdj != nil {
+		path := fmt.Sprintf("/proc/%d/oom_score_adj", pid)
+		if err := os.WriteFile(path, []byte(strconv.Itoa(*config.OOMScoreAdj)), 0644); err != nil {
+			return fmt.Errorf("failed to set OOM score adjustment: %v", err)
+		}
+	}
+
+	// Nice value, CPU affinity and I/O priority are per thread on Linux, so apply them to
+	// all of the init's threads, including the one that will execute the target
+	for _, tid := range processThreads(pid) {
+		if config.Nice != nil {
+			if err := unix.Setpriority(unix.PRIO_PROCESS, tid, *config.Nice); err != nil {
+				return fmt.Errorf("failed to set nice value: %v", err)
+			}
+		}
+
+		if !config.CPUAffinity.isEmpty() {
+			var set unix.CPUSet
+			for _, cpu := range config.CPUAffinity.cpus() {
+				set.Set(cpu)
+			}
+			if err := unix.SchedSetaffinity(tid, &set); err != nil {
+				return fmt.Errorf("failed to set CPU affinity: %v", err)
+			}
+		}
+
+		if config.IOClass != "" {
+			level := 4 // the kernel's default level
+			if config.IOLevel != nil {
+				level = *config.IOLevel
+			}
+			if config.IOClass == "idle" {
+				level = 0
+			}
+			prio := ioprioClasses[config.IOClass]<<ioprioClassShift | level
+			if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(prio)); errno != 0 {
+				return fmt.Errorf("failed to set I/O priority: %v", errno)
+			}
+		}
+	}
+	return nil
+}
+
+// processThreads lists the thread IDs of a process, falling back to just its PID
+func processThreads(pid int) []int {
+	entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return []int{pid}
+	}
+
+	var tids []int
+	for _, entry := range entries {
+		if tid, err := strconv.Atoi(entry.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	if len(tids) == 0 {
+		return []int{pid}
+	}
+	return tids
+}
+
+// defaultSignalDisposition resets the signal to its default action behind the Go runtime's
+// back, which otherwise turns signals like SIGSEGV into a crash report with exit code 2.
+// Only meant for re-raising a signal to terminate the launcher.
+func defaultSignalDisposition(signal syscall.Signal) bool {
+	// All zeros is SIG_DFL without flags on every architecture, whatever the struct layout
+	var action [8]uint64
+	sigsetSize := uintptr(8)
+	if strings.HasPrefix(runtime.GOARCH, "mips") {
+		sigsetSize = 16
+	}
+	_, _, errno := unix.RawSyscall6(unix.SYS_RT_SIGACTION, uintptr(signal), uintptr(unsafe.Pointer(&action)), 0, sigsetSize, 0, 0)
+	if errno != 0 {
+		return false
+	}
+
+	var unblock unix.Sigset_t
+	bit, wordBits := uint(signal)-1, uint(unsafe.Sizeof(unblock.Val[0])*8)
+	unblock.Val[bit/wordBits] = 1 << (bit % wordBits)
+	return unix.PthreadSigmask(unix.SIG_UNBLOCK, &unblock, nil) == nil
+}
@@ -0,0 +1,168 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// startSleeper starts a long-running process to apply settings to and stops it when the test ends
+func startSleeper(t *testing.T) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	return cmd
+}
+
+// readProcFile reads a file from /proc/<pid>
+func readProcFile(t *testing.T, pid int, name string) string {
+	t.Helper()
+	content, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/" + name)
+	if err != nil {
+		t.Fatalf("Failed to read /proc/%d/%s: %v", pid, name, err)
+	}
+	return string(content)
+}
+
+// TestTuneStartedProcessLimits tests that resource limits and nice are applied to a running process
+func TestTuneStartedProcessLimits(t *testing.T) {
+	file := writeTempConfig(t, `
+target = app
+limit.nofile = 256:512
+limit.core = 0
+limit.cpu = 1h
+limit.as = unlimited
+nice = 5
+`)
+	config, err := parseConfig(file)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	cmd := startSleeper(t)
+	if err := tuneStartedProcess(cmd.Process.Pid, config); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	limits := readProcFile(t, cmd.Process.Pid, "limits")
+	for _, expected := range [][]string{
+		{"Max open files", "256", "512", "files"},
+		{"Max core file size", "0", "0", "bytes"},
+		{"Max cpu time", "3600", "3600", "seconds"},
+		{"Max address space", "unlimited", "unlimited", "bytes"},
+	} {
+		found := false
+		for _, line := range strings.Split(limits, "\n") {
+			if strings.HasPrefix(line, expected[0]) {
+				found = true
+				if fields := strings.Fields(strings.TrimPrefix(line, expected[0])); strings.Join(fields, " ") != strings.Join(expected[1:], " ") {
+					t.Errorf("Expected %q limit %v, got %q", expected[0], expected[1:], line)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("Limit %q not found in %q", expected[0], limits)
+		}
+	}
+
+	// Field 19 of /proc/<pid>/stat is the nice value, counted after the parenthesized command name
+	stat := readProcFile(t, cmd.Process.Pid, "stat")
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if fields[16] != "5" {
+		t.Errorf("Expected nice value 5, got %s", fields[16])
+	}
+}
+
+// TestLaunchProcessSettingsBeforeExec tests that the target runs with its limits and nice value
+// from its very first instruction, as the init applies them before executing it
+func TestLaunchProcessSettingsBeforeExec(t *testing.T) {
+	t.Setenv(runtimeDirEnvVar, t.TempDir())
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "ulimit -n; nice")
+	}
+
+	nice := 7
+	config := &Configuration{Target: "sh", Nice: &nice, Limits: map[string]ResourceLimit{"nofile": {Soft: 64, Hard: 128}}}
+	var output bytes.Buffer
+	launcher := NewLauncher(config)
+	launcher.Stdout = &output
+	if err := launcher.Launch(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if output.String() != "64\n7\n" {
+		t.Errorf("Expected nofile limit 64 and nice 7, got %q", output.String())
+	}
+}
+
+// TestPrepareProcessStartUmask tests that the umask is inherited by the target and restored afterwards
+func TestPrepareProcessStartUmask(t *testing.T) {
+	umask := 0027
+	restore := prepareProcessStart(&Configuration{Umask: &umask})
+	output, err := exec.Command("sh", "-c", "umask").Output()
+	restore()
+	if err != nil {
+		t.Fatalf("Failed to run shell: %v", err)
+	}
+	if strings.TrimSpace(string(output)) != "0027" {
+		t.Errorf("Expected umask 0027, got %q", output)
+	}
+
+	output, _ = exec.Command("sh", "-c", "umask").Output()
+	if strings.TrimSpace(string(output)) == "0027" {
+		t.Errorf("Expected launcher umask to be restored")
+	}
+}
+
+// TestTuneStartedProcessScheduling tests CPU affinity, I/O priority and OOM score, read back via /proc/<pid>
+func TestTuneStartedProcessScheduling(t *testing.T) {
+	file := writeTempConfig(t, `
+target = app
+cpuAffinity = 0
+ioClass = best-effort
+ioLevel = 6
+oomScoreAdj = 500
+`)
+	config, err := parseConfig(file)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	cmd := startSleeper(t)
+	pid := cmd.Process.Pid
+	if err := tuneStartedProcess(pid, config); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if score := strings.TrimSpace(readProcFile(t, pid, "oom_score_adj")); score != "500" {
+		t.Errorf("Expected oom_score_adj 500, got %s", score)
+	}
+
+	status := readProcFile(t, pid, "status")
+	if !strings.Contains(status, "Cpus_allowed_list:\t0\n") {
+		t.Errorf("Expected CPU affinity 0, got status %q", status)
+	}
+
+	prio, _, errno := unix.Syscall(unix.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(pid), 0)
+	if errno != 0 {
+		t.Fatalf("Failed to read I/O priority: %v", errno)
+	}
+	if expected := uintptr(ioprioClasses["best-effort"]<<ioprioClassShift | 6); prio != expected {
+		t.Errorf("Expected I/O priority %#x, got %#x", expected, prio)
+	}
+}
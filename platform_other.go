@@ -1,10 +1,11 @@
-//go:build !windows
-// +build !windows
+//go:build !windows && !linux
+// +build !windows,!linux
 
 package main
 
 import (
 	"os/exec"
+	"syscall"
 )
 
 // hideTargetWindow is a no-op on non-Windows platforms
@@ -13,3 +14,21 @@ func hideTargetWindow(cmd *exec.Cmd) {
 	// Intentionally empty - hiding windows is only supported on Windows
 	// This function exists to provide a consistent API across platforms
 }
+
+// prepareProcessStart is a no-op since umask is only applied on Linux
+func prepareProcessStart(config *Configuration) func() {
+	return func() {}
+}
+
+// tuneStartedProcess only warns about settings that are supported on Linux only
+func tuneStartedProcess(pid int, config *Configuration) error {
+	if config.hasLinuxProcessSettings() {
+		logf("resource limits, nice, umask, CPU affinity, I/O priority and OOM score are only supported on Linux, ignoring them")
+	}
+	return nil
+}
+
+// defaultSignalDisposition can't reset signal actions of the Go runtime on this platform
+func defaultSignalDisposition(signal syscall.Signal) bool {
+	return false
+}
@@ -13,3 +13,16 @@ import (
 func hideTargetWindow(cmd *exec.Cmd) {
 	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
 }
+
+// prepareProcessStart is a no-op since umask is only applied on Linux
+func prepareProcessStart(config *Configuration) func() {
+	return func() {}
+}
+
+// tuneStartedProcess only warns about settings that are supported on Linux only
+func tuneStartedProcess(pid int, config *Configuration) error {
+	if config.hasLinuxProcessSettings() {
+		logf("resource limits, nice, umask, CPU affinity, I/O priority and OOM score are only supported on Linux, ignoring them")
+	}
+	return nil
+}
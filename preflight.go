@@ -0,0 +1,177 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"debug/elf"
+	"debug/pe"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// elfMachines maps GOARCH values to the ELF machine type of native binaries
+var elfMachines = map[string]elf.Machine{
+	"386":     elf.EM_386,
+	"amd64":   elf.EM_X86_64,
+	"arm":     elf.EM_ARM,
+	"arm64":   elf.EM_AARCH64,
+	"loong64": elf.EM_LOONGARCH,
+	"mips":    elf.EM_MIPS,
+	"mipsle":  elf.EM_MIPS,
+	"mips64":  elf.EM_MIPS,
+	"ppc64":   elf.EM_PPC64,
+	"ppc64le": elf.EM_PPC64,
+	"riscv64": elf.EM_RISCV,
+	"s390x":   elf.EM_S390,
+}
+
+// peMachines maps GOARCH values to the PE machine type of native binaries
+var peMachines = map[string]uint16{
+	"386":   pe.IMAGE_FILE_MACHINE_I386,
+	"amd64": pe.IMAGE_FILE_MACHINE_AMD64,
+	"arm":   pe.IMAGE_FILE_MACHINE_ARMNT,
+	"arm64": pe.IMAGE_FILE_MACHINE_ARM64,
+}
+
+// compatibleELFArchs lists the architectures whose ELF binaries a host architecture can run as well
+var compatibleELFArchs = map[string][]string{
+	"amd64": {"386"},
+	"arm64": {"arm"},
+}
+
+// compatiblePEArchs lists the architectures whose Windows binaries a host architecture can run
+// as well, including the x86 emulation of Windows on ARM
+var compatiblePEArchs = map[string][]string{
+	"amd64": {"386"},
+	"arm64": {"arm", "amd64", "386"},
+}
+
+// preflightTarget checks that the target can actually be executed on this system: it must be
+// executable, a script's interpreter must exist and a binary must match the architecture.
+// Targets run through a configured interpreter only need the interpreter to be found.
+func preflightTarget(path string, interpreter []string) error {
+	if _, err := os.Stat(path); err != nil {
+		return nil // missing files are reported elsewhere
+	}
+
+	if interpreter != nil {
+		if _, err := exec.LookPath(interpreter[0]); err != nil {
+			return fmt.Errorf("interpreter %s for target %s not found", interpreter[0], path)
+		}
+		return nil
+	}
+
+	if err := checkExecPermission(path); err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error reading target %s: %v", path, err)
+	}
+	defer file.Close()
+
+	header := make([]byte, 4)
+	n, _ := file.Read(header)
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("#!")):
+		return checkShebang(path, file)
+	case bytes.HasPrefix(header, []byte(elf.ELFMAG)):
+		return checkELF(path, file)
+	case bytes.HasPrefix(header, []byte("MZ")):
+		return checkPE(path, file)
+	}
+	return nil
+}
+
+// checkShebang verifies that the interpreter named in a script's "#!" line exists.
+// For "#!/usr/bin/env program" lines, the program must be found in PATH as well.
+func checkShebang(path string, file *os.File) error {
+	if runtime.GOOS == "windows" {
+		return nil // Windows doesn't execute scripts through their "#!" line
+	}
+
+	if _, err := file.Seek(0, 0); err != nil {
+		return fmt.Errorf("error reading target %s: %v", path, err)
+	}
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("error reading target %s: %v", path, err)
+	}
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimRight(line, "\r\n"), "#!"))
+	if len(fields) == 0 {
+		return fmt.Errorf("target script %s has an empty interpreter line", path)
+	}
+
+	interpreter := fields[0]
+	if info, err := os.Stat(interpreter); err != nil || info.IsDir() {
+		return fmt.Errorf("interpreter %s of target script %s not found", interpreter, path)
+	}
+	if strings.HasSuffix(interpreter, "/env") && len(fields) > 1 && !strings.HasPrefix(fields[1], "-") {
+		if _, err := exec.LookPath(fields[1]); err != nil {
+			return fmt.Errorf("interpreter %s of target script %s not found in PATH", fields[1], path)
+		}
+	}
+	return nil
+}
+
+// checkELF verifies that an ELF binary can run on this operating system and architecture
+func checkELF(path string, file *os.File) error {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return fmt.Errorf("target %s is an ELF binary, which can't be executed on %s", path, runtime.GOOS)
+	}
+
+	binary, err := elf.NewFile(file)
+	if err != nil {
+		return fmt.Errorf("target %s is not a valid ELF binary: %v", path, err)
+	}
+	if binary.Type != elf.ET_EXEC && binary.Type != elf.ET_DYN {
+		return fmt.Errorf("target %s is not an executable but an ELF file of type %s", path, binary.Type)
+	}
+
+	native, known := elfMachines[runtime.GOARCH]
+	if !known || binary.Machine == native {
+		return nil
+	}
+	if slices.ContainsFunc(compatibleELFArchs[runtime.GOARCH], func(arch string) bool { return elfMachines[arch] == binary.Machine }) {
+		return nil
+	}
+	return fmt.Errorf("target %s is built for architecture %s, which can't run on %s", path, binary.Machine, runtime.GOARCH)
+}
+
+// checkPE verifies that a PE binary can run on this operating system and architecture
+func checkPE(path string, file *os.File) error {
+	if runtime.GOOS != "windows" {
+		return fmt.Errorf("target %s is a Windows executable, which can't be executed on %s", path, runtime.GOOS)
+	}
+
+	binary, err := pe.NewFile(file)
+	if err != nil {
+		return fmt.Errorf("target %s is not a valid Windows executable: %v", path, err)
+	}
+	if binary.Characteristics&pe.IMAGE_FILE_EXECUTABLE_IMAGE == 0 || binary.Characteristics&pe.IMAGE_FILE_DLL != 0 {
+		return fmt.Errorf("target %s is not an executable program", path)
+	}
+
+	native, known := peMachines[runtime.GOARCH]
+	if !known || binary.Machine == native {
+		return nil
+	}
+	if slices.ContainsFunc(compatiblePEArchs[runtime.GOARCH], func(arch string) bool { return peMachines[arch] == binary.Machine }) {
+		return nil
+	}
+	machine := fmt.Sprintf("0x%04x", binary.Machine)
+	for arch, peMachine := range peMachines {
+		if peMachine == binary.Machine {
+			machine = arch
+		}
+	}
+	return fmt.Errorf("target %s is built for architecture %s, which can't run on %s", path, machine, runtime.GOARCH)
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+// +build !unix
+
+package main
+
+// checkExecPermission is a no-op on non-Unix platforms, which have no execute permission bits
+func checkExecPermission(path string) error {
+	return nil
+}
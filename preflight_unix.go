@@ -0,0 +1,18 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkExecPermission verifies that the current user may execute the file
+func checkExecPermission(path string) error {
+	if err := unix.Access(path, unix.X_OK); err != nil {
+		return fmt.Errorf("target %s is not executable: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// startInPty starts the command on a new pseudo-terminal and returns a function waiting
+// for it to finish. The command's Stdin and Stdout are relayed through the pty; since a
+// terminal has a single output stream, the target's stderr ends up in Stdout as well.
+func startInPty(cmd *exec.Cmd) (func() error, error) {
+	master, slave, err := openPty()
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate pseudo-terminal: %v", err)
+	}
+
+	// Undo everything set up here once the command is done or failed to start
+	var cleanups []func()
+	cleanup := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+		master.Close()
+	}
+
+	input, output := cmd.Stdin, cmd.Stdout
+	terminal, isTerminal := input.(*os.File)
+	isTerminal = isTerminal && isTerminalFile(terminal)
+
+	if isTerminal {
+		// Mirror the real terminal's size and keep it in sync while the target runs
+		resizePty(terminal, master)
+		resize := make(chan os.Signal, 1)
+		signal.Notify(resize, syscall.SIGWINCH)
+		cleanups = append(cleanups, func() {
+			signal.Stop(resize)
+			close(resize)
+		})
+		go func() {
+			for range resize {
+				resizePty(terminal, master)
+			}
+		}()
+
+		// Pass keystrokes through unprocessed, the pty applies the line discipline
+		restore, err := makeRaw(terminal)
+		if err != nil {
+			slave.Close()
+			cleanup()
+			return nil, fmt.Errorf("failed to put terminal into raw mode: %v", err)
+		}
+		cleanups = append(cleanups, restore)
+	} else {
+		// Input isn't typed and output isn't displayed, so keep both as they are
+		if err := makeNonInteractive(slave); err != nil {
+			slave.Close()
+			cleanup()
+			return nil, fmt.Errorf("failed to configure pseudo-terminal: %v", err)
+		}
+	}
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0 // the child's stdin, which is the pty
+
+	err = cmd.Start()
+	slave.Close() // the child holds its own copy; reads from master end once it's gone
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	stopInput := func() {}
+	if input != nil {
+		// Forward end of input as the terminal's EOF character
+		stopInput = relayInput(master, input, !isTerminal)
+	}
+	outputDone := make(chan struct{})
+	go func() {
+		// Reading the master fails with EIO once all slave handles are closed
+		_, _ = io.Copy(output, master)
+		close(outputDone)
+	}()
+
+	return func() error {
+		err := cmd.Wait()
+		<-outputDone
+		// Stop reading input before the terminal is restored, so the next keystroke isn't
+		// taken for the target that's gone
+		stopInput()
+		cleanup()
+		return err
+	}, nil
+}
+
+// relayInput copies input to the pty master in the background, writing the EOF character
+// when input ends if forwardEOF is set. The returned function stops the relay without
+// reading any further input. Files are polled along with a wake-up pipe to allow that; other
+// readers can't be interrupted and keep being relayed until they end.
+func relayInput(master *os.File, input io.Reader, forwardEOF bool) func() {
+	var raw syscall.RawConn
+	var wakeReader, wakeWriter *os.File
+	err := errors.New("not a file")
+	if file, isFile := input.(*os.File); isFile {
+		if raw, err = file.SyscallConn(); err == nil {
+			wakeReader, wakeWriter, err = os.Pipe()
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ended := true
+		if err != nil {
+			_, _ = io.Copy(master, input)
+		} else {
+			// Holding the file descriptor keeps it from being closed and reused meanwhile
+			_ = raw.Control(func(fd uintptr) {
+				ended = copyUntilWoken(master, int(fd), int(wakeReader.Fd()))
+			})
+		}
+		if ended && forwardEOF {
+			_, _ = master.Write([]byte{4})
+		}
+	}()
+	if err != nil {
+		return func() {}
+	}
+
+	return func() {
+		_, _ = wakeWriter.Write([]byte{1})
+		<-done
+		wakeWriter.Close()
+		wakeReader.Close()
+	}
+}
+
+// copyUntilWoken copies from fd to out until fd ends or wakeFd becomes readable, and reports
+// whether fd ended
+func copyUntilWoken(out io.Writer, fd, wakeFd int) bool {
+	buffer := make([]byte, 32*1024)
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}, {Fd: int32(wakeFd), Events: unix.POLLIN}}
+	for {
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			return true
+		}
+		if fds[1].Revents != 0 {
+			return false
+		}
+		if fds[0].Revents == 0 {
+			continue
+		}
+
+		n, err := unix.Read(fd, buffer)
+		if errors.Is(err, unix.EINTR) || errors.Is(err, unix.EAGAIN) {
+			continue
+		}
+		if err != nil || n == 0 {
+			return true
+		}
+		if _, err := out.Write(buffer[:n]); err != nil {
+			return true
+		}
+	}
+}
+
+// openPty allocates a new pseudo-terminal pair
+func openPty() (master, slave *os.File, err error) {
+	masterFd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	master = os.NewFile(uintptr(masterFd), "/dev/ptmx")
+
+	if err := unix.IoctlSetPointerInt(masterFd, unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	number, err := unix.IoctlGetInt(masterFd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	slavePath := fmt.Sprintf("/dev/pts/%d", number)
+	slaveFd, err := unix.Open(slavePath, unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, os.NewFile(uintptr(slaveFd), slavePath), nil
+}
+
+// isTerminalFile reports whether the file refers to a terminal
+func isTerminalFile(file *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(file.Fd()), unix.TCGETS)
+	return err == nil
+}
+
+// resizePty copies the window size of the terminal to the pty
+func resizePty(terminal, pty *os.File) {
+	size, err := unix.IoctlGetWinsize(int(terminal.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return
+	}
+	_ = unix.IoctlSetWinsize(int(pty.Fd()), unix.TIOCSWINSZ, size)
+}
+
+// makeRaw puts the terminal into raw mode and returns a function restoring its previous state
+func makeRaw(terminal *os.File) (func(), error) {
+	fd := int(terminal.Fd())
+	original, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+
+	// Equivalent of cfmakeraw(3)
+	raw := *original
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = unix.IoctlSetTermios(fd, unix.TCSETS, original)
+	}, nil
+}
+
+// makeNonInteractive turns off input echoing and the "\n" to "\r\n" output translation
+func makeNonInteractive(terminal *os.File) error {
+	fd := int(terminal.Fd())
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+	termios.Lflag &^= unix.ECHO
+	termios.Oflag &^= unix.ONLCR
+	return unix.IoctlSetTermios(fd, unix.TCSETS, termios)
+}
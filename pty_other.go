@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// startInPty is not supported on non-Linux platforms
+func startInPty(cmd *exec.Cmd) (func() error, error) {
+	return nil, fmt.Errorf("pty mode is only supported on Linux")
+}
@@ -0,0 +1,161 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// descendantsKillGrace is how long leftover descendants get to exit after SIGTERM before SIGKILL
+const descendantsKillGrace = 5 * time.Second
+
+// descendantsPollInterval is how often the process tree is rescanned while terminating descendants
+const descendantsPollInterval = 50 * time.Millisecond
+
+// trackDescendants makes the launcher the subreaper of the target's process tree if the
+// descendants policy needs it, so processes orphaned below the target are reparented to
+// the launcher instead of init
+func trackDescendants(config *Configuration) error {
+	if config.Descendants != "wait" && config.Descendants != "kill" {
+		return nil
+	}
+	if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to become child subreaper: %v", err)
+	}
+	return nil
+}
+
+// handleDescendants applies the descendants policy to what the started target leaves behind.
+// With "kill", leftovers are terminated as soon as the target exits, even while the launcher
+// still waits for output they hold open. The returned function completes the policy; call it
+// once the target has been waited for.
+func handleDescendants(pid int, config *Configuration) func() {
+	switch config.Descendants {
+	case "wait":
+		return func() {
+			if count := len(descendantsOf(os.Getpid())); count > 0 {
+				logf("waiting for %d remaining descendant processes of the target", count)
+			}
+			reapChildren(0)
+		}
+	case "kill":
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			// Wait for the target to exit without reaping it, exec.Cmd does that
+			var info unix.Siginfo
+			for {
+				err := unix.Waitid(unix.P_PID, pid, &info, unix.WEXITED|unix.WNOWAIT, nil)
+				if !errors.Is(err, unix.EINTR) {
+					break
+				}
+			}
+			terminateDescendants(pid)
+		}()
+		return func() {
+			<-done
+			reapChildren(unix.WNOHANG)
+		}
+	}
+	return func() {}
+}
+
+// terminateDescendants sends SIGTERM to all descendants of the launcher except the exited
+// target, and SIGKILL to those still running after the grace period. It returns once all
+// of them are gone, reaping the ones reparented to the launcher.
+func terminateDescendants(target int) {
+	self := os.Getpid()
+	deadline := time.Now().Add(descendantsKillGrace)
+	signaled := make(map[int]unix.Signal)
+
+	for {
+		var remaining []int
+		for _, pid := range descendantsOf(self) {
+			if pid != target {
+				remaining = append(remaining, pid)
+			}
+		}
+		if len(remaining) == 0 {
+			return
+		}
+		if len(signaled) == 0 {
+			logf("terminating %d remaining descendant processes of the target", len(remaining))
+		}
+
+		signal := unix.SIGTERM
+		if time.Now().After(deadline) {
+			signal = unix.SIGKILL
+		}
+		for _, pid := range remaining {
+			if signaled[pid] != signal {
+				_ = unix.Kill(pid, signal)
+				signaled[pid] = signal
+			}
+			// Only reap by PID, waiting for any child could take the target from exec.Cmd
+			var status unix.WaitStatus
+			_, _ = unix.Wait4(pid, &status, unix.WNOHANG, nil)
+		}
+		time.Sleep(descendantsPollInterval)
+	}
+}
+
+// reapChildren waits for all remaining child processes of the launcher, or only collects
+// the ones that already exited if options contains WNOHANG
+func reapChildren(options int) {
+	for {
+		var status unix.WaitStatus
+		pid, err := unix.Wait4(-1, &status, options, nil)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil || pid <= 0 {
+			return
+		}
+	}
+}
+
+// descendantsOf returns the PIDs of all living processes below the given one, by scanning /proc
+func descendantsOf(ancestor int) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	children := make(map[int][]int)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			continue
+		}
+		// The command name may contain anything, the fields after it are "state ppid ..."
+		fields := bytes.Fields(stat[bytes.LastIndexByte(stat, ')')+1:])
+		if len(fields) < 2 || string(fields[0]) == "Z" {
+			continue
+		}
+		if ppid, err := strconv.Atoi(string(fields[1])); err == nil {
+			children[ppid] = append(children[ppid], pid)
+		}
+	}
+
+	var descendants []int
+	queue := children[ancestor]
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		descendants = append(descendants, pid)
+		queue = append(queue, children[pid]...)
+	}
+	return descendants
+}
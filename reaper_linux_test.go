@@ -0,0 +1,68 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// TestDescendants tests waiting for and terminating the processes a target leaves behind
+func TestDescendants(t *testing.T) {
+	tempDir := t.TempDir()
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+
+	t.Run("Wait", func(t *testing.T) {
+		marker := filepath.Join(tempDir, "marker")
+		execCommand = func(command string, args ...string) *exec.Cmd {
+			return exec.Command("sh", "-c", "(sleep 0.3; touch "+marker+") >/dev/null 2>&1 &")
+		}
+
+		launcher := NewLauncher(&Configuration{Target: "sh", Descendants: "wait"})
+		if err := launcher.Launch(); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if _, err := os.Stat(marker); err != nil {
+			t.Errorf("Expected launcher to wait for the background process: %v", err)
+		}
+		if remaining := descendantsOf(os.Getpid()); len(remaining) > 0 {
+			t.Errorf("Expected no remaining descendants, got %v", remaining)
+		}
+	})
+
+	t.Run("Kill", func(t *testing.T) {
+		pidFile := filepath.Join(tempDir, "pid")
+		execCommand = func(command string, args ...string) *exec.Cmd {
+			return exec.Command("sh", "-c", "sleep 30 & echo $! > "+pidFile)
+		}
+
+		// The background process holds the logged output open, which must not delay the kill
+		launcher := NewLauncher(&Configuration{Target: "sh", Descendants: "kill", StdoutLog: filepath.Join(tempDir, "stdout.log")})
+		start := time.Now()
+		if err := launcher.Launch(); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > descendantsKillGrace {
+			t.Errorf("Expected leftover process to be terminated right away, took %v", elapsed)
+		}
+
+		content, _ := os.ReadFile(pidFile)
+		pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			t.Fatalf("Expected background PID, got %q", content)
+		}
+		if err := unix.Kill(pid, 0); err == nil {
+			_ = unix.Kill(pid, unix.SIGKILL)
+			t.Errorf("Expected background process %d to be terminated", pid)
+		}
+	})
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+// trackDescendants only warns about a descendants policy, which is supported on Linux only
+func trackDescendants(config *Configuration) error {
+	if config.Descendants == "wait" || config.Descendants == "kill" {
+		logf("descendants policy is only supported on Linux, ignoring it")
+	}
+	return nil
+}
+
+// handleDescendants does nothing on non-Linux platforms
+func handleDescendants(pid int, config *Configuration) func() {
+	return func() {}
+}
@@ -0,0 +1,140 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for the delay between attempts
+const (
+	defaultRetryDelay    = time.Second
+	defaultRetryBackoff  = 2.0
+	defaultRetryMaxDelay = time.Minute
+)
+
+// RetryConfig reruns a target that fails with one of the given exit codes
+type RetryConfig struct {
+	ExitCodes []int         // exit codes that are worth another attempt
+	Retries   int           // attempts after the first one, at most
+	Delay     time.Duration // before the first retry
+	Backoff   float64       // factor the delay grows by with every retry
+	MaxDelay  time.Duration // upper bound of the delay
+}
+
+// parseExitCodes parses a comma-separated list of positive exit codes
+func parseExitCodes(key, value string) ([]int, error) {
+	var codes []int
+	for _, part := range strings.Split(value, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || code <= 0 {
+			return nil, fmt.Errorf("invalid %s value %q, must be exit codes other than 0 like '75,111'", key, value)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// checkRetrySettings checks that retries are fully configured, and fills in the default delays
+func (c *Configuration) checkRetrySettings() error {
+	retry := &c.Retry
+	if retry.Retries == 0 && len(retry.ExitCodes) == 0 {
+		return nil
+	}
+	if len(retry.ExitCodes) == 0 {
+		return fmt.Errorf("retryOnExitCodes must be specified when retries is set")
+	}
+	if retry.Retries == 0 {
+		return fmt.Errorf("retries must be specified when retryOnExitCodes is set")
+	}
+	if c.Detach {
+		return fmt.Errorf("retries can't be used with detach")
+	}
+
+	if retry.Delay == 0 {
+		retry.Delay = defaultRetryDelay
+	}
+	if retry.Backoff == 0 {
+		retry.Backoff = defaultRetryBackoff
+	}
+	if retry.MaxDelay == 0 {
+		retry.MaxDelay = defaultRetryMaxDelay
+	}
+	return nil
+}
+
+// runTargetWithRetries runs the target, rerunning it after a growing delay while it fails with
+// one of the retried exit codes. It returns the result of the last attempt.
+func (l *Launcher) runTargetWithRetries() error {
+	retry := &l.Config.Retry
+	if retry.Retries == 0 {
+		return l.runTarget()
+	}
+
+	rewindStdin := stdinRewinder(l.Stdin)
+	attempts := retry.Retries + 1
+	delay := retry.Delay
+	target := l
+	for attempt := 1; ; attempt++ {
+		err := target.runTarget()
+		code := exitCodeOf(err)
+		if attempt == attempts || !slices.Contains(retry.ExitCodes, code) {
+			logf("attempt %d of %d: target exited with code %d", attempt, attempts, code)
+			return err
+		}
+
+		if rewindErr := rewindStdin(); rewindErr != nil {
+			logf("attempt %d of %d: target exited with code %d, not retrying as %v", attempt, attempts, code, rewindErr)
+			return err
+		}
+		logf("attempt %d of %d: target exited with code %d, retrying in %v", attempt, attempts, code, delay)
+
+		// Only the first attempt truncates the output logs, so they keep the output of every attempt
+		if attempt == 1 && l.Config.OutputLogMode == "truncate" {
+			config := *l.Config
+			config.OutputLogMode = "append"
+			launcher := *l
+			launcher.Config = &config
+			target = &launcher
+		}
+		time.Sleep(delay)
+		delay = min(time.Duration(float64(delay)*retry.Backoff), retry.MaxDelay)
+	}
+}
+
+// stdinRewinder returns a function preparing the target's stdin for another attempt. Input
+// from a terminal or the null device can be used again as is and a file is rewound to where
+// the first attempt started reading, but a pipe or socket the target read from is used up.
+func stdinRewinder(stdin io.Reader) func() error {
+	if stdin == nil {
+		return func() error { return nil }
+	}
+	if file, ok := stdin.(*os.File); ok {
+		info, err := file.Stat()
+		if err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			return func() error { return nil }
+		}
+		if err != nil || !info.Mode().IsRegular() {
+			return func() error { return fmt.Errorf("stdin may have been consumed") }
+		}
+	}
+	seeker, ok := stdin.(io.Seeker)
+	if !ok {
+		return func() error { return fmt.Errorf("stdin may have been consumed") }
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return func() error { return fmt.Errorf("stdin can't be rewound: %v", err) }
+	}
+	return func() error {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return fmt.Errorf("stdin can't be rewound: %v", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,179 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Route is a routing rule selecting the target to launch based on the received arguments.
+// A route matches when all of its conditions do; a route without conditions always matches.
+type Route struct {
+	Index int
+
+	// Conditions
+	FirstArg    *regexp.Regexp // glob the first received argument must match, nil if unset
+	AnyArg      *regexp.Regexp // glob at least one received argument must match, nil if unset
+	ArgCountMin int            // minimum number of received arguments, -1 if unset
+	ArgCountMax int            // maximum number of received arguments, -1 if unbounded
+
+	// Launch settings used when the route matches
+	Target         string
+	TargetSha256   string
+	ExtraArgs      string
+	ExtraArgsOrder string
+}
+
+// splitGroupKey splits a key of a numbered settings group, like "route.2.target" for group
+// "route", into its index and lowercased setting
+func splitGroupKey(group, key string) (int, string, bool) {
+	parts := strings.SplitN(key, ".", 3)
+	if len(parts) != 3 || !strings.EqualFold(parts[0], group) {
+		return 0, "", false
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 0 {
+		return 0, "", false
+	}
+	return index, strings.ToLower(parts[2]), true
+}
+
+// set applies a single "route.<n>.<setting>=value" line to the route
+func (r *Route) set(setting, key, value string) error {
+	var err error
+	switch setting {
+	case "firstarg":
+		r.FirstArg, err = parseArgPattern(key, value)
+	case "anyarg":
+		r.AnyArg, err = parseArgPattern(key, value)
+	case "argcount":
+		r.ArgCountMin, r.ArgCountMax, err = parseArgCount(key, value)
+	case "target":
+		r.Target = value
+	case "targetsha256":
+		r.TargetSha256, err = parseSha256(key, value)
+	case "extraargs":
+		r.ExtraArgs = value
+	case "extraargsorder":
+		r.ExtraArgsOrder, err = parseChoice(key, value, "before", "after")
+	default:
+		err = fmt.Errorf("unknown route setting in config: %s", key)
+	}
+	return err
+}
+
+// parseArgPattern compiles a glob pattern matched against received arguments. Unlike file
+// globs, "*" and "?" also match "/", as arguments are often paths or URLs.
+func parseArgPattern(key, value string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '\\':
+			if i+1 == len(value) {
+				return nil, fmt.Errorf("invalid %s pattern %q: trailing backslash", key, value)
+			}
+			i++
+			expr.WriteString(regexp.QuoteMeta(value[i : i+1]))
+		case '[':
+			end := strings.IndexByte(value[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid %s pattern %q: unterminated character class", key, value)
+			}
+			class := value[i+1 : i+1+end]
+			i += end + 1
+			negated := strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^")
+			if negated {
+				class = class[1:]
+			}
+			expr.WriteString("[")
+			if negated {
+				expr.WriteString("^")
+			}
+			expr.WriteString(strings.NewReplacer(`\`, `\\`, `[`, `\[`, `^`, `\^`).Replace(class))
+			expr.WriteString("]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(value[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+
+	pattern, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s pattern %q: %v", key, value, err)
+	}
+	return pattern, nil
+}
+
+// parseArgCount parses an argument count condition: "N" (exactly), "N-M" (range) or "N+" (at least)
+func parseArgCount(key, value string) (int, int, error) {
+	invalid := fmt.Errorf("invalid %s value %q, must be like '2', '1-3' or '1+'", key, value)
+
+	if minStr, found := strings.CutSuffix(value, "+"); found {
+		minCount, err := strconv.Atoi(minStr)
+		if err != nil || minCount < 0 {
+			return 0, 0, invalid
+		}
+		return minCount, -1, nil
+	}
+
+	minStr, maxStr, isRange := strings.Cut(value, "-")
+	minCount, err := strconv.Atoi(minStr)
+	if err != nil || minCount < 0 {
+		return 0, 0, invalid
+	}
+	if !isRange {
+		return minCount, minCount, nil
+	}
+	maxCount, err := strconv.Atoi(maxStr)
+	if err != nil || maxCount < minCount {
+		return 0, 0, invalid
+	}
+	return minCount, maxCount, nil
+}
+
+// matches reports whether the route's conditions all hold for the received arguments
+func (r *Route) matches(args []string) bool {
+	if r.FirstArg != nil {
+		if len(args) == 0 || !r.FirstArg.MatchString(args[0]) {
+			return false
+		}
+	}
+
+	if r.AnyArg != nil {
+		found := false
+		for _, arg := range args {
+			if r.AnyArg.MatchString(arg) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if r.ArgCountMin >= 0 && len(args) < r.ArgCountMin {
+		return false
+	}
+	if r.ArgCountMax >= 0 && len(args) > r.ArgCountMax {
+		return false
+	}
+	return true
+}
+
+// selectRoute returns the first route matching the received arguments, or nil if none does
+func (c *Configuration) selectRoute(args []string) *Route {
+	for i := range c.Routes {
+		if c.Routes[i].matches(args) {
+			return &c.Routes[i]
+		}
+	}
+	return nil
+}
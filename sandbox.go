@@ -0,0 +1,17 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+// sandboxInitEnvVar carries the sandbox setup to the launcher re-executed inside the new namespaces
+const sandboxInitEnvVar = "PROXYLAUNCHER_SANDBOX_INIT"
+
+// SandboxConfig describes the namespace sandbox the target runs in
+type SandboxConfig struct {
+	NoNetwork  bool     // new network namespace with only the loopback interface
+	PrivateTmp bool     // private tmpfs mounted on /tmp
+	ReadOnly   []string // paths bind-mounted read-only over themselves
+}
+
+// enabled reports whether any sandbox feature is configured
+func (s *SandboxConfig) enabled() bool {
+	return s.NoNetwork || s.PrivateTmp || len(s.ReadOnly) > 0
+}
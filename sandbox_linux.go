@@ -0,0 +1,355 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// idMapSize covers all user and group IDs, as far as an int can on 32-bit platforms
+const idMapSize = min(1<<32-1, math.MaxInt)
+
+// sandboxInit is what the re-executed launcher sets up inside the namespaces before
+// executing the target in its place
+type sandboxInit struct {
+	Path       string
+	Args       []string
+	Sandbox    SandboxConfig
+	Credential *syscall.Credential // switched to after setup, as mounting needs the namespace's root
+	Landlock   *landlockRuleset    // applied last, right before executing the target
+	ListenPid  bool                // set LISTEN_PID to the target's PID for socket activation
+	StartFd    int                 // pipe to wait on until the launcher tuned the process, 0 if none
+}
+
+// applySandbox makes the command run in new user, mount and (optionally) network namespaces,
+// and restricts its filesystem access with Landlock. Since mounts and network setup have to
+// happen inside the namespaces and Landlock applies to the restricted process itself, the
+// launcher re-executes itself (see runSandboxInitIfRequested), prepares everything and then
+// executes the target. This is also how LISTEN_PID gets set to the target's PID for socket
+// activation if setListenPid is true, as the PID isn't known before, and how resource limits
+// and scheduling settings are in place before the target runs: the init waits until the
+// launcher has applied them to it. Call the returned function once the command has started,
+// with whether the target may be executed.
+func applySandbox(cmd *exec.Cmd, config *Configuration, setListenPid bool) (func(proceed bool), error) {
+	release := func(bool) {}
+	gated := tunesStartedProcess(config)
+	if !config.Sandbox.enabled() && !config.FsAllow.enabled() && !setListenPid && !gated {
+		return release, nil
+	}
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+	if config.Sandbox.enabled() {
+		if err := checkUserNamespaces(); err != nil {
+			return nil, fmt.Errorf("sandbox can't be set up: %v", err)
+		}
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	setup := sandboxInit{
+		Path:      cmd.Path,
+		Args:      cmd.Args,
+		Sandbox:   config.Sandbox,
+		ListenPid: setListenPid,
+	}
+	if config.FsAllow.enabled() {
+		var err error
+		if setup.Landlock, err = newLandlockRuleset(cmd, config); err != nil {
+			return nil, err
+		}
+	}
+	if !config.Sandbox.enabled() && setup.Landlock == nil && !setListenPid && !gated {
+		return release, nil // Landlock is unavailable and only warned about
+	}
+	if config.Sandbox.enabled() {
+		setup.Credential, cmd.SysProcAttr.Credential = cmd.SysProcAttr.Credential, nil
+	}
+
+	if gated {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create start pipe: %v", err)
+		}
+		setup.StartFd = firstExtraFd + len(cmd.ExtraFiles)
+		cmd.ExtraFiles = append(cmd.ExtraFiles, reader)
+		release = func(proceed bool) {
+			reader.Close()
+			if proceed {
+				_, _ = writer.Write([]byte{1})
+			}
+			writer.Close()
+		}
+	}
+
+	spec, err := json.Marshal(setup)
+	if err != nil {
+		release(false)
+		return nil, err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		release(false)
+		return nil, fmt.Errorf("failed to determine executable path: %v", err)
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = setEnv(env, sandboxInitEnvVar, string(spec))
+	cmd.Path = self
+	if !config.Sandbox.enabled() {
+		return release, nil // no namespaces, the init runs directly with the target's credentials
+	}
+
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS
+	if config.Sandbox.NoNetwork {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+
+	// Keep IDs unchanged inside the user namespace. Root may map everything, which lets
+	// it switch to runAsUser later; other users can only map themselves.
+	if os.Geteuid() == 0 {
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: 0, Size: idMapSize}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: 0, Size: idMapSize}}
+		cmd.SysProcAttr.GidMappingsEnableSetgroups = true
+	} else {
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	}
+	return release, nil
+}
+
+// checkUserNamespaces reports why user namespaces can't be created, if the system disables them
+func checkUserNamespaces() error {
+	type sysctlCheck struct{ path, disabled, reason string }
+	checks := []sysctlCheck{
+		{"/proc/sys/user/max_user_namespaces", "0", "user namespaces are disabled (user.max_user_namespaces is 0)"},
+		{"/proc/sys/kernel/unprivileged_userns_clone", "0", "unprivileged user namespaces are disabled (kernel.unprivileged_userns_clone is 0)"},
+	}
+	if os.Geteuid() != 0 {
+		checks = append(checks, sysctlCheck{
+			"/proc/sys/kernel/apparmor_restrict_unprivileged_userns", "1",
+			"unprivileged user namespaces are restricted by AppArmor (kernel.apparmor_restrict_unprivileged_userns is 1)",
+		})
+	}
+
+	for _, check := range checks {
+		if value, err := os.ReadFile(check.path); err == nil && strings.TrimSpace(string(value)) == check.disabled {
+			return errors.New(check.reason)
+		}
+	}
+	if _, err := os.Stat("/proc/self/ns/user"); err != nil {
+		return errors.New("the kernel doesn't support user namespaces")
+	}
+	return nil
+}
+
+// runSandboxInitIfRequested sets up the sandbox and executes the target in place of this
+// process if the launcher was re-executed inside the sandbox namespaces. It only returns
+// if no sandbox setup was requested.
+func runSandboxInitIfRequested() {
+	spec, requested := os.LookupEnv(sandboxInitEnvVar)
+	if !requested {
+		return
+	}
+
+	// Landlock and no_new_privs apply to a single thread, which has to execute the target
+	runtime.LockOSThread()
+
+	var setup sandboxInit
+	err := json.Unmarshal([]byte(spec), &setup)
+	if err == nil {
+		err = setup.run()
+	}
+	fmt.Fprintf(os.Stderr, "proxylauncher: failed to set up sandbox: %v\n", err)
+	os.Exit(127)
+}
+
+// run performs the sandbox setup and executes the target, only returning on failure
+func (s *sandboxInit) run() error {
+	// Resource limits and scheduling settings are applied by the launcher meanwhile
+	if s.StartFd > 0 {
+		if err := waitForStart(s.StartFd); err != nil {
+			return err
+		}
+	}
+
+	if s.Sandbox.enabled() {
+		if err := s.setupNamespaces(); err != nil {
+			return err
+		}
+	}
+
+	if s.Landlock != nil {
+		if err := s.Landlock.restrictSelf(); err != nil {
+			return err
+		}
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, entry := range os.Environ() {
+		if !strings.HasPrefix(entry, sandboxInitEnvVar+"=") {
+			env = append(env, entry)
+		}
+	}
+	if s.ListenPid {
+		// Executing the target keeps the PID
+		env = setEnv(env, "LISTEN_PID", strconv.Itoa(os.Getpid()))
+	}
+	return syscall.Exec(s.Path, s.Args, env)
+}
+
+// waitForStart blocks until the launcher allows executing the target through the start pipe
+func waitForStart(fd int) error {
+	defer unix.Close(fd)
+	var proceed [1]byte
+	for {
+		n, err := unix.Read(fd, proceed[:])
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("waiting for the launcher: %v", err)
+		}
+		if n == 0 {
+			return errors.New("the launcher aborted the start")
+		}
+		return nil
+	}
+}
+
+// setupNamespaces prepares mounts, network and credentials inside the new namespaces
+func (s *sandboxInit) setupNamespaces() error {
+	// Keep all mount changes inside this namespace
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("making mounts private: %v", err)
+	}
+
+	if s.Sandbox.PrivateTmp {
+		if err := unix.Mount("tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+			return fmt.Errorf("mounting private /tmp: %v", err)
+		}
+	}
+
+	for _, path := range s.Sandbox.ReadOnly {
+		if err := bindReadOnly(path); err != nil {
+			return fmt.Errorf("mounting %s read-only: %v", path, err)
+		}
+	}
+
+	if s.Sandbox.NoNetwork {
+		if err := bringUpLoopback(); err != nil {
+			return fmt.Errorf("bringing up loopback interface: %v", err)
+		}
+	}
+
+	// The target would otherwise keep all capabilities in the namespace when running as its
+	// root, and could undo the mounts above
+	if err := dropCapabilities(); err != nil {
+		return fmt.Errorf("dropping capabilities: %v", err)
+	}
+
+	if s.Credential != nil {
+		if err := switchCredential(s.Credential); err != nil {
+			return fmt.Errorf("switching user: %v", err)
+		}
+	}
+	return nil
+}
+
+// dropCapabilities makes sure the target executed by this thread gets no capabilities,
+// even as root: it empties the bounding, inheritable and ambient sets and sets no_new_privs.
+// The effective set is kept, so the user can still be switched afterwards.
+func dropCapabilities() error {
+	for capability := 0; ; capability++ {
+		// Reading past the last capability the kernel knows fails
+		if _, err := unix.PrctlRetInt(unix.PR_CAPBSET_READ, uintptr(capability), 0, 0, 0); err != nil {
+			break
+		}
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(capability), 0, 0, 0); err != nil {
+			return err
+		}
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return err
+	}
+
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&header, &data[0]); err != nil {
+		return err
+	}
+	for i := range data {
+		data[i].Inheritable = 0
+	}
+	if err := unix.Capset(&header, &data[0]); err != nil {
+		return err
+	}
+	return unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+}
+
+// bindReadOnly bind-mounts a path over itself and makes the mount read-only
+func bindReadOnly(path string) error {
+	if err := unix.Mount(path, path, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return err
+	}
+
+	// Inside a user namespace, the remount has to keep the flags the mount is locked to
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return err
+	}
+	locked := uintptr(stat.Flags) & (unix.ST_NOSUID | unix.ST_NODEV | unix.ST_NOEXEC | unix.ST_NOATIME | unix.ST_NODIRATIME | unix.ST_RELATIME)
+	return unix.Mount("", path, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|locked, "")
+}
+
+// bringUpLoopback enables the loopback interface of the current network namespace
+func bringUpLoopback() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	ifreq, err := unix.NewIfreq("lo")
+	if err != nil {
+		return err
+	}
+	if err := unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifreq); err != nil {
+		return err
+	}
+	ifreq.SetUint16(ifreq.Uint16() | unix.IFF_UP | unix.IFF_RUNNING)
+	return unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifreq)
+}
+
+// switchCredential changes to the given groups, group and user, in that order
+func switchCredential(credential *syscall.Credential) error {
+	if !credential.NoSetGroups {
+		groups := make([]int, len(credential.Groups))
+		for i, group := range credential.Groups {
+			groups[i] = int(group)
+		}
+		if err := syscall.Setgroups(groups); err != nil {
+			return err
+		}
+	}
+	if err := syscall.Setgid(int(credential.Gid)); err != nil {
+		return err
+	}
+	return syscall.Setuid(int(credential.Uid))
+}
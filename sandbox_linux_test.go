@@ -0,0 +1,97 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain lets the test binary act as the sandbox init, as the launcher re-executes itself,
+// and records the descriptors passed to it like the launcher does
+func TestMain(m *testing.M) {
+	runSandboxInitIfRequested()
+	recordInheritedFds()
+	os.Exit(m.Run())
+}
+
+// TestSandbox tests that the target runs without network, with a private /tmp and read-only paths
+func TestSandbox(t *testing.T) {
+	if err := checkUserNamespaces(); err != nil {
+		t.Skipf("User namespaces not available: %v", err)
+	}
+	if err := exec.Command("unshare", "-Urn", "true").Run(); err != nil {
+		t.Skipf("User namespaces can't be created: %v", err)
+	}
+
+	tempDir := t.TempDir()
+	readOnlyDir := filepath.Join(tempDir, "readonly")
+	os.Mkdir(readOnlyDir, 0755)
+
+	originalExecCommand := execCommand
+	defer func() { execCommand = originalExecCommand }()
+
+	tests := []struct {
+		name     string
+		sandbox  SandboxConfig
+		script   string
+		expected []string
+	}{
+		{
+			name:     "No Network",
+			sandbox:  SandboxConfig{NoNetwork: true},
+			script:   "tail -n +3 /proc/net/dev | cut -d: -f1 | tr -d ' '",
+			expected: []string{"lo"},
+		},
+		{
+			name:     "Private Tmp",
+			sandbox:  SandboxConfig{PrivateTmp: true},
+			script:   "touch /tmp/sandbox-test-marker && echo tmp writable; ls /tmp",
+			expected: []string{"tmp writable", "sandbox-test-marker"},
+		},
+		{
+			// Paths under /tmp would be hidden by a private /tmp, so this runs separately
+			name:     "Read-only Path",
+			sandbox:  SandboxConfig{ReadOnly: []string{readOnlyDir}},
+			script:   "touch " + readOnlyDir + "/file 2>/dev/null || echo readonly denied; ls " + readOnlyDir,
+			expected: []string{"readonly denied"},
+		},
+		{
+			// Even as root, the target has no capabilities to undo the sandbox's mounts
+			name:    "Read-only Path Can't Be Remounted",
+			sandbox: SandboxConfig{ReadOnly: []string{readOnlyDir}},
+			script: "grep CapEff /proc/self/status | tr -d '\\t'; mount -o remount,bind,rw " + readOnlyDir + " 2>/dev/null; " +
+				"umount " + readOnlyDir + " 2>/dev/null; touch " + readOnlyDir + "/file 2>/dev/null || echo readonly denied",
+			expected: []string{"CapEff:0000000000000000", "readonly denied"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			execCommand = func(command string, args ...string) *exec.Cmd {
+				return exec.Command("sh", "-c", tc.script)
+			}
+
+			logPath := filepath.Join(tempDir, "stdout.log")
+			launcher := NewLauncher(&Configuration{Target: "sh", Sandbox: tc.sandbox, StdoutLog: logPath, OutputLogMode: "truncate"})
+			if err := launcher.Launch(); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			content, _ := os.ReadFile(logPath)
+			lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+			if strings.Join(lines, "|") != strings.Join(tc.expected, "|") {
+				t.Errorf("Expected output %q, got %q", tc.expected, lines)
+			}
+		})
+	}
+
+	if _, err := os.Stat("/tmp/sandbox-test-marker"); err == nil {
+		os.Remove("/tmp/sandbox-test-marker")
+		t.Errorf("Expected /tmp of the sandbox to be private")
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// applySandbox fails if a sandbox is configured, which is only supported on Linux, as are
+// Landlock filesystem restrictions and setting LISTEN_PID for socket activation
+func applySandbox(cmd *exec.Cmd, config *Configuration, setListenPid bool) (func(proceed bool), error) {
+	if config.Sandbox.enabled() {
+		return nil, fmt.Errorf("sandbox is only supported on Linux")
+	}
+	if config.FsAllow.enabled() {
+		if err := config.FsAllow.unsupported("Landlock is only supported on Linux, running the target without filesystem restrictions"); err != nil {
+			return nil, err
+		}
+	}
+	return func(bool) {}, nil
+}
+
+// runSandboxInitIfRequested does nothing on non-Linux platforms
+func runSandboxInitIfRequested() {}
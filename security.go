@@ -0,0 +1,46 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// enforceSecurityPolicy checks that the config file, its directory and the targets can't be
+// modified by other users, and refuses or warns about them according to securityPolicy
+func enforceSecurityPolicy(configPath string, config *Configuration) error {
+	if config.SecurityPolicy == "ignore" {
+		return nil
+	}
+
+	type checkedPath struct{ description, path string }
+	paths := []checkedPath{
+		{"config file", configPath},
+		{"config directory", filepath.Dir(configPath)},
+		{"target", config.Target},
+	}
+	for _, route := range config.Routes {
+		if route.Target != config.Target {
+			paths = append(paths, checkedPath{fmt.Sprintf("target of route %d", route.Index), route.Target})
+		}
+	}
+
+	var problems []string
+	for _, p := range paths {
+		for _, problem := range insecurePermissions(p.path) {
+			problems = append(problems, fmt.Sprintf("%s %s %s", p.description, p.path, problem))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if config.SecurityPolicy == "warn" {
+		for _, problem := range problems {
+			logf("insecure permissions: %s", problem)
+		}
+		return nil
+	}
+	return fmt.Errorf("refusing to launch due to insecure permissions: %s", strings.Join(problems, "; "))
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+// +build !unix
+
+package main
+
+// insecurePermissions is a no-op on non-Unix platforms, whose permissions
+// are ACL based and not checked by ProxyLauncher
+func insecurePermissions(path string) []string {
+	return nil
+}
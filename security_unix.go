@@ -0,0 +1,91 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Group and user databases consulted for group members, variables for testing
+var (
+	groupFilePath  = "/etc/group"
+	passwdFilePath = "/etc/passwd"
+)
+
+// insecurePermissions describes why a file or directory could be modified by users other
+// than the current one or root. Group write access is accepted if the group is root's or has
+// no members besides the current user, and world-writable directories are accepted if they are sticky.
+func insecurePermissions(path string) []string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil // missing files are reported elsewhere
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	var problems []string
+	uid, gid := int(stat.Uid), int(stat.Gid)
+	if uid != 0 && uid != os.Getuid() {
+		problems = append(problems, fmt.Sprintf("is owned by uid %d", uid))
+	}
+
+	mode := info.Mode()
+	stickyDir := info.IsDir() && mode&os.ModeSticky != 0
+	if mode.Perm()&0002 != 0 && !stickyDir {
+		problems = append(problems, "is world-writable")
+	}
+	if mode.Perm()&0020 != 0 && gid != 0 && !stickyDir && groupHasOtherMembers(gid) {
+		problems = append(problems, fmt.Sprintf("is writable by group %d", gid))
+	}
+	return problems
+}
+
+// groupHasOtherMembers reports whether users other than the current one belong to the group,
+// either listed as members or with it as their primary group. Groups that can't be looked up
+// in the local databases, like directory service groups, are assumed to have other members.
+func groupHasOtherMembers(gid int) bool {
+	current, err := user.Current()
+	if err != nil {
+		return true
+	}
+	others := func(path string, matches func(fields []string) []string) bool {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return true
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			for _, name := range matches(strings.Split(line, ":")) {
+				if name != "" && name != current.Username {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	gidStr := strconv.Itoa(gid)
+	found := false
+	if others(groupFilePath, func(fields []string) []string {
+		if len(fields) < 4 || fields[2] != gidStr {
+			return nil
+		}
+		found = true
+		return strings.Split(fields[3], ",")
+	}) || !found {
+		return true
+	}
+	return others(passwdFilePath, func(fields []string) []string {
+		if len(fields) < 4 || fields[3] != gidStr {
+			return nil
+		}
+		return fields[:1]
+	})
+}
@@ -0,0 +1,69 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// TestSecurityPolicySharedGroup tests that group write access is only accepted for a group
+// without other members
+func TestSecurityPolicySharedGroup(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("Current user unknown: %v", err)
+	}
+	tempDir := t.TempDir()
+	target := filepath.Join(tempDir, "app")
+	os.WriteFile(target, []byte("dummy executable"), 0775)
+	os.Chmod(target, 0775)
+	configPath := filepath.Join(tempDir, "proxylauncher.cfg")
+	os.WriteFile(configPath, []byte("target="+target+"\n"), 0644)
+
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("Failed to stat target: %v", err)
+	}
+	gid := int(info.Sys().(*syscall.Stat_t).Gid)
+	if gid == 0 {
+		if os.Getuid() != 0 {
+			t.Skip("Test needs a target group other than root")
+		}
+		gid = 4321
+		os.Chown(target, -1, gid)
+	}
+
+	originalGroupFile, originalPasswdFile := groupFilePath, passwdFilePath
+	defer func() { groupFilePath, passwdFilePath = originalGroupFile, originalPasswdFile }()
+	groupFilePath = filepath.Join(t.TempDir(), "group")
+	passwdFilePath = filepath.Join(t.TempDir(), "passwd")
+	os.WriteFile(passwdFilePath, []byte(fmt.Sprintf("%s:x:%d:%d::/home/me:/bin/sh\n", current.Username, os.Getuid(), gid)), 0644)
+
+	// The user's private group is fine
+	os.WriteFile(groupFilePath, []byte(fmt.Sprintf("me:x:%d:%s\n", gid, current.Username)), 0644)
+	if _, err := loadConfig(configPath); err != nil {
+		t.Errorf("Expected private group to be accepted, got: %v", err)
+	}
+
+	// A primary group shared with other users isn't
+	os.WriteFile(groupFilePath, []byte(fmt.Sprintf("users:x:%d:\n", gid)), 0644)
+	os.WriteFile(passwdFilePath, []byte(fmt.Sprintf("%s:x:%d:%d::/home/me:/bin/sh\nalice:x:1234:%d::/home/alice:/bin/sh\n",
+		current.Username, os.Getuid(), gid, gid)), 0644)
+	_, err = loadConfig(configPath)
+	if expected := fmt.Sprintf("target %s is writable by group %d", target, gid); err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected shared group error, got: %v", err)
+	}
+
+	// So is a group not in the local database
+	os.WriteFile(groupFilePath, nil, 0644)
+	if _, err := loadConfig(configPath); err == nil {
+		t.Errorf("Expected unknown group to be refused")
+	}
+}
@@ -0,0 +1,159 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runtimeDirEnvVar overrides the directory status records are kept in
+const runtimeDirEnvVar = "PROXYLAUNCHER_RUNTIME_DIR"
+
+// statusRecord describes a running target, stored as JSON in the runtime directory
+type statusRecord struct {
+	Name        string    `json:"name"`
+	Profile     string    `json:"profile"`
+	ConfigPath  string    `json:"configPath"`
+	Args        []string  `json:"args"`
+	Pid         int       `json:"pid"`
+	LauncherPid int       `json:"launcherPid"`
+	StartTime   time.Time `json:"startTime"`
+
+	// ProcessStart identifies the process along with its PID, which the system reuses once
+	// the target is gone. It's empty where the platform doesn't tell when a process started.
+	ProcessStart string `json:"processStart,omitempty"`
+}
+
+// running reports whether the recorded target still runs, rather than another process
+// that got its PID
+func (r *statusRecord) running() bool {
+	if r.Pid <= 0 || !processAlive(r.Pid) {
+		return false
+	}
+	return r.ProcessStart == "" || processStartTime(r.Pid) == r.ProcessStart
+}
+
+// launcherProfile names the launcher by how it was invoked, which for a launcher renamed
+// to stand in for a program is that program's name
+func launcherProfile() string {
+	name := filepath.Base(os.Args[0])
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// runtimeDir returns the directory status records are kept in, creating it if needed.
+// It must not be writable by other users, who could otherwise forge records to be signaled.
+func runtimeDir() (string, error) {
+	dir := os.Getenv(runtimeDirEnvVar)
+	if dir == "" {
+		dir = defaultRuntimeDir()
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("error creating runtime directory: %v", err)
+	}
+	if problems := insecurePermissions(dir); len(problems) > 0 {
+		return "", fmt.Errorf("runtime directory %s %s", dir, strings.Join(problems, " and "))
+	}
+	return dir, nil
+}
+
+// writeStatusRecord stores the record in the runtime directory and returns a function removing it again
+func writeStatusRecord(record statusRecord) (func(), error) {
+	dir, err := runtimeDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, record.Name+".json")
+	if err := writeFileAtomically(path, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("error writing status record: %v", err)
+	}
+	return func() { os.Remove(path) }, nil
+}
+
+// listStatusRecords returns the records of all running targets, oldest first, and removes
+// the records of targets that are gone
+func listStatusRecords() ([]statusRecord, error) {
+	dir, err := runtimeDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []statusRecord
+	for _, path := range paths {
+		var record statusRecord
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // removed meanwhile
+		}
+		if err == nil {
+			err = json.Unmarshal(data, &record)
+		}
+		if err != nil || !record.running() {
+			os.Remove(path)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	slices.SortFunc(records, func(a, b statusRecord) int { return a.StartTime.Compare(b.StartTime) })
+	return records, nil
+}
+
+// formatStatusRecords renders the records as a table
+func formatStatusRecords(records []statusRecord) string {
+	if len(records) == 0 {
+		return "No running targets\n"
+	}
+
+	var table strings.Builder
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tPID\tSTARTED\tCONFIG\tCOMMAND")
+	for _, record := range records {
+		fmt.Fprintf(writer, "%s\t%d\t%s\t%s\t%s\n", record.Name, record.Pid,
+			record.StartTime.Local().Format(time.DateTime), record.ConfigPath, strings.Join(record.Args, " "))
+	}
+	writer.Flush()
+	return table.String()
+}
+
+// stopTargets asks the running targets to terminate whose record or profile has the given
+// name, and returns a summary of the signaled targets
+func stopTargets(name string) (string, error) {
+	records, err := listStatusRecords()
+	if err != nil {
+		return "", err
+	}
+
+	var stopped []string
+	for _, record := range records {
+		if record.Name != name && record.Profile != name {
+			continue
+		}
+		// The target may have exited since it was listed
+		if !record.running() {
+			continue
+		}
+		if err := terminateProcess(record.Pid); err != nil {
+			return "", fmt.Errorf("error stopping %s (PID %d): %v", record.Name, record.Pid, err)
+		}
+		stopped = append(stopped, fmt.Sprintf("Stopped %s (PID %d)", record.Name, record.Pid))
+	}
+	if len(stopped) == 0 {
+		return "", fmt.Errorf("no running target named %q", name)
+	}
+	return strings.Join(stopped, "\n") + "\n", nil
+}
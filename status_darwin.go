@@ -0,0 +1,21 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// processStartTime returns when the process started, to tell it apart from a later process
+// reusing its PID. It returns "" if the process is gone.
+func processStartTime(pid int) string {
+	info, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil || info.Proc.P_pid != int32(pid) {
+		return ""
+	}
+	start := info.Proc.P_starttime
+	return fmt.Sprintf("%d.%06d", start.Sec, start.Usec)
+}
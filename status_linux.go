@@ -0,0 +1,30 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// processStartTime returns when the process started, in clock ticks since boot, to tell it
+// apart from a later process reusing its PID. It returns "" if the process is gone.
+func processStartTime(pid int) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return ""
+	}
+	// The command name in field 2 may contain spaces and parentheses, so the fields are
+	// counted from its last closing parenthesis, which field 3 follows
+	stat := string(data)
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return ""
+	}
+	if fields := strings.Fields(stat[end+1:]); len(fields) > 22-3 {
+		return fields[22-3]
+	}
+	return ""
+}
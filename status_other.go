@@ -0,0 +1,9 @@
+//go:build unix && !linux && !darwin
+// +build unix,!linux,!darwin
+
+package main
+
+// processStartTime isn't supported on this platform, so records are matched by PID alone
+func processStartTime(pid int) string {
+	return ""
+}
@@ -0,0 +1,25 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// defaultRuntimeDir returns the user's runtime directory, or a per-user directory in the
+// temporary directory on systems without one
+func defaultRuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "proxylauncher")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("proxylauncher-%d", os.Getuid()))
+}
+
+// terminateProcess asks a process to terminate
+func terminateProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
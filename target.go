@@ -0,0 +1,212 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// targetSeparator separates the candidates of a target list
+const targetSeparator = "|"
+
+// resolveTarget picks the executable to launch from a target setting, which is an ordered
+// list of candidates separated by "|". Candidates may be glob patterns, in which case the
+// match with the highest version wins. The first candidate that exists is returned.
+func resolveTarget(target string) (string, error) {
+	var tried []string
+	for _, candidate := range strings.Split(target, targetSeparator) {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" {
+			continue
+		}
+
+		if !isGlobPattern(candidate) {
+			if fileExistsFunc(candidate) {
+				return candidate, nil
+			}
+			tried = append(tried, candidate)
+			continue
+		}
+
+		matches, err := filepath.Glob(candidate)
+		if err != nil {
+			return "", fmt.Errorf("invalid target pattern %q: %v", candidate, err)
+		}
+		matches = slices.DeleteFunc(matches, func(match string) bool { return !fileExistsFunc(match) })
+		if len(matches) == 0 {
+			tried = append(tried, candidate+" (no matches)")
+			continue
+		}
+
+		// Newest version first
+		slices.SortFunc(matches, func(a, b string) int { return compareVersions(b, a) })
+		return matches[0], nil
+	}
+
+	if len(tried) == 1 {
+		return "", fmt.Errorf("target executable not found: %s", tried[0])
+	}
+	return "", fmt.Errorf("target executable not found, tried: %s", strings.Join(tried, ", "))
+}
+
+// isGlobPattern reports whether a path contains glob meta characters
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// compareVersions compares two strings the way version numbers are ordered: runs of
+// digits are compared numerically, everything else character by character. So
+// "tool-1.10" sorts after "tool-1.9", which sorts after "tool-1.2". A pre-release like
+// "tool-1.2-rc1" sorts before its release, with or without a file extension.
+func compareVersions(a, b string) int {
+	sawNumber := false
+	for a != "" && b != "" {
+		aChunk, aRest := nextVersionChunk(a)
+		bChunk, bRest := nextVersionChunk(b)
+
+		aNum, aErr := strconv.ParseUint(aChunk, 10, 64)
+		bNum, bErr := strconv.ParseUint(bChunk, 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if aNum != bNum {
+				if aNum < bNum {
+					return -1
+				}
+				return 1
+			}
+			sawNumber = true
+		case aChunk != bChunk:
+			if sawNumber {
+				return compareVersionSuffixes(a, b)
+			}
+			return strings.Compare(aChunk, bChunk)
+		}
+		a, b = aRest, bRest
+	}
+	if sawNumber {
+		return compareVersionSuffixes(a, b)
+	}
+	return strings.Compare(a, b)
+}
+
+// compareVersionSuffixes orders what differs after the same version number: a pre-release
+// tag like "-rc1", "~beta" or "rc1" sorts before anything else, like the end of the name or
+// an extension. Other suffixes are compared character by character.
+func compareVersionSuffixes(a, b string) int {
+	aPre, bPre := isPreReleaseSuffix(a), isPreReleaseSuffix(b)
+	switch {
+	case aPre && !bPre:
+		return -1
+	case bPre && !aPre:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// isPreReleaseSuffix reports whether the rest of a name after a version number starts with
+// a pre-release tag: a letter, optionally after "-" or "~"
+func isPreReleaseSuffix(s string) bool {
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "~") {
+		s = s[1:]
+	}
+	return s != "" && unicode.IsLetter(rune(s[0]))
+}
+
+// nextVersionChunk splits off the leading run of either digits or non-digits
+func nextVersionChunk(s string) (string, string) {
+	digits := unicode.IsDigit(rune(s[0]))
+	end := 1
+	for end < len(s) && unicode.IsDigit(rune(s[end])) == digits {
+		end++
+	}
+	return s[:end], s[end:]
+}
+
+// parseSha256 validates a hex-encoded SHA-256 digest config value, empty meaning not pinned
+func parseSha256(key, value string) (string, error) {
+	digest := strings.ToLower(value)
+	if digest == "" {
+		return "", nil
+	}
+	if _, err := hex.DecodeString(digest); err != nil || len(digest) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid %s value %q, must be a hex-encoded SHA-256 digest", key, value)
+	}
+	return digest, nil
+}
+
+// fileSha256 returns the hex-encoded SHA-256 digest of a file's content
+func fileSha256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifyTargetChecksum checks that the target's content matches the expected digest, if one is pinned
+func verifyTargetChecksum(target, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	actual, err := fileSha256(target)
+	if err != nil {
+		return fmt.Errorf("error computing checksum of target: %v", err)
+	}
+	if actual != expected {
+		return fmt.Errorf("target checksum mismatch for %s: expected sha256 %s, got %s", target, expected, actual)
+	}
+	return nil
+}
+
+// pinTargetChecksums computes the checksums of the currently resolved targets and
+// writes them into the configuration file as targetSha256 (and route.<n>.targetSha256
+// for routes with their own target). It returns a summary of the pinned digests.
+func pinTargetChecksums(configPath string) (string, error) {
+	config, err := readConfig(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	values := make(map[string]string)
+	var summary []string
+	pin := func(key, target string) error {
+		digest, err := fileSha256(target)
+		if err != nil {
+			return fmt.Errorf("error computing checksum of target: %v", err)
+		}
+		values[key] = digest
+		summary = append(summary, fmt.Sprintf("%s: sha256 %s", target, digest))
+		return nil
+	}
+
+	if err := pin("targetSha256", config.Target); err != nil {
+		return "", err
+	}
+	for _, route := range config.Routes {
+		if route.Target == config.Target {
+			continue
+		}
+		if err := pin(fmt.Sprintf("route.%d.targetSha256", route.Index), route.Target); err != nil {
+			return "", err
+		}
+	}
+
+	if err := setConfigValues(configPath, values); err != nil {
+		return "", fmt.Errorf("error writing config file: %v", err)
+	}
+	return "Pinned target checksums:\n" + strings.Join(summary, "\n"), nil
+}
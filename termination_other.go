@@ -0,0 +1,9 @@
+//go:build !unix
+// +build !unix
+
+package main
+
+import "os"
+
+// mirrorTerminationSignal does nothing on platforms without signals
+func mirrorTerminationSignal(state *os.ProcessState) {}
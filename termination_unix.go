@@ -0,0 +1,40 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// mirrorTerminationSignal makes the launcher die by the signal that terminated the target,
+// so shells and callers see the same "killed by signal" status. Where the signal can't be
+// re-raised, the launcher exits with 128 plus the signal number like a shell would. It only
+// returns if the target wasn't terminated by a signal.
+func mirrorTerminationSignal(state *os.ProcessState) {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return
+	}
+
+	signal := status.Signal()
+	if status.CoreDump() {
+		logf("target terminated by signal %d (%v), core dumped", int(signal), signal)
+	} else {
+		logf("target terminated by signal %d (%v)", int(signal), signal)
+	}
+
+	if defaultSignalDisposition(signal) {
+		// The target already dumped its core if it was going to, the launcher doesn't need to
+		_ = unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{})
+		_ = syscall.Kill(os.Getpid(), signal)
+
+		// Delivery to another thread may take a moment
+		time.Sleep(100 * time.Millisecond)
+	}
+	os.Exit(128 + int(signal))
+}
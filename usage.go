@@ -0,0 +1,41 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// usageReport summarizes the resources a finished target used, in the style of "time -v"
+func usageReport(args []string, state *os.ProcessState, wallTime time.Duration) string {
+	cpuPercent := 0
+	if wallTime > 0 {
+		cpuPercent = int(100 * (state.UserTime() + state.SystemTime()) / wallTime)
+	}
+
+	lines := []string{
+		fmt.Sprintf("Command being timed: %q", strings.Join(args, " ")),
+		fmt.Sprintf("User time (seconds): %.2f", state.UserTime().Seconds()),
+		fmt.Sprintf("System time (seconds): %.2f", state.SystemTime().Seconds()),
+		fmt.Sprintf("Percent of CPU this job got: %d%%", cpuPercent),
+		fmt.Sprintf("Elapsed (wall clock) time: %v", wallTime.Round(time.Millisecond)),
+	}
+	lines = append(lines, processCounters(state)...)
+	if state.Exited() {
+		lines = append(lines, fmt.Sprintf("Exit status: %d", state.ExitCode()))
+	} else {
+		lines = append(lines, fmt.Sprintf("Command terminated: %v", state))
+	}
+	return "resource usage of target:\n\t" + strings.Join(lines, "\n\t")
+}
+
+// reportUsage writes the usage report to the launcher log, and to stderr as well if configured
+func reportUsage(config *Configuration, args []string, state *os.ProcessState, wallTime time.Duration) {
+	report := usageReport(args, state, wallTime)
+	logf("%s", report)
+	if config.ReportUsage == "stderr" && !logsToStderr() {
+		fmt.Fprintf(os.Stderr, "%s%s\n", launcherLogPrefix, report)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+// +build !unix
+
+package main
+
+import "os"
+
+// processCounters returns nothing, as only CPU times are available on this platform
+func processCounters(state *os.ProcessState) []string {
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// processCounters returns the memory, page fault and context switch counters of a finished process
+func processCounters(state *os.ProcessState) []string {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return nil
+	}
+
+	// Most systems report the maximum resident set size in kilobytes, Apple's in bytes
+	maxRSS := int64(usage.Maxrss)
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		maxRSS /= 1024
+	}
+
+	return []string{
+		fmt.Sprintf("Maximum resident set size (kbytes): %d", maxRSS),
+		fmt.Sprintf("Major (requiring I/O) page faults: %d", int64(usage.Majflt)),
+		fmt.Sprintf("Minor (reclaiming a frame) page faults: %d", int64(usage.Minflt)),
+		fmt.Sprintf("Voluntary context switches: %d", int64(usage.Nvcsw)),
+		fmt.Sprintf("Involuntary context switches: %d", int64(usage.Nivcsw)),
+	}
+}
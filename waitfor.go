@@ -0,0 +1,166 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for conditions that don't set their own timeout and poll interval
+const (
+	defaultWaitTimeout  = 30 * time.Second
+	defaultWaitInterval = time.Second
+)
+
+// WaitCondition is a dependency that must become ready before the target is launched.
+// Exactly one of File, TCP, Unix and Command is set.
+type WaitCondition struct {
+	Index int
+
+	File    string // path that must exist
+	TCP     string // host:port that must accept connections
+	Unix    string // socket path that must accept connections
+	Command string // command line that must exit with code 0
+
+	Timeout  time.Duration // how long to wait in total, 0 if unset
+	Interval time.Duration // time between checks, 0 if unset
+}
+
+// set applies a single "waitFor.<n>.<setting>=value" line to the condition
+func (w *WaitCondition) set(setting, key, value string) error {
+	var err error
+	switch setting {
+	case "file":
+		w.File = value
+	case "tcp":
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			return fmt.Errorf("invalid %s value %q, %v", key, value, err)
+		}
+		w.TCP = value
+	case "unix":
+		w.Unix = value
+	case "command":
+		if len(parseArgs(value)) == 0 {
+			return fmt.Errorf("empty command in config: %s", key)
+		}
+		w.Command = value
+	case "timeout":
+		w.Timeout, err = parseDuration(key, value)
+	case "interval":
+		w.Interval, err = parseDuration(key, value)
+	default:
+		err = fmt.Errorf("unknown waitFor setting in config: %s", key)
+	}
+	return err
+}
+
+// validate checks that the condition says what to wait for, and fills in the default timing
+func (w *WaitCondition) validate() error {
+	kinds := 0
+	for _, value := range []string{w.File, w.TCP, w.Unix, w.Command} {
+		if value != "" {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("waitFor %d must set exactly one of file, tcp, unix or command", w.Index)
+	}
+	if w.Timeout == 0 {
+		w.Timeout = defaultWaitTimeout
+	}
+	if w.Interval == 0 {
+		w.Interval = defaultWaitInterval
+	}
+	return nil
+}
+
+// String describes the condition for log and error messages
+func (w *WaitCondition) String() string {
+	switch {
+	case w.File != "":
+		return "file " + w.File
+	case w.TCP != "":
+		return "tcp " + w.TCP
+	case w.Unix != "":
+		return "unix socket " + w.Unix
+	default:
+		return fmt.Sprintf("command %q", w.Command)
+	}
+}
+
+// check tests the condition once, returning why it isn't ready. The check gives up at the deadline.
+func (w *WaitCondition) check(deadline time.Time) error {
+	switch {
+	case w.File != "":
+		_, err := os.Stat(w.File)
+		return err
+	case w.TCP != "", w.Unix != "":
+		network, address := "tcp", w.TCP
+		if w.Unix != "" {
+			network, address = "unix", w.Unix
+		}
+		conn, err := net.DialTimeout(network, address, time.Until(deadline))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	default:
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		defer cancel()
+		args := parseArgs(w.Command)
+		return execCommandContext(ctx, args[0], args[1:]...).Run()
+	}
+}
+
+// wait checks the condition until it's ready or its timeout has passed
+func (w *WaitCondition) wait() error {
+	start := time.Now()
+	deadline := start.Add(w.Timeout)
+	for attempt := 1; ; attempt++ {
+		err := w.check(deadline)
+		if err == nil {
+			if attempt > 1 {
+				logf("%s ready after %v", w, time.Since(start).Round(time.Millisecond))
+			}
+			return nil
+		}
+		if attempt == 1 {
+			logf("waiting for %s", w)
+		}
+		if time.Now().Add(w.Interval).After(deadline) {
+			return fmt.Errorf("%s not ready after %v: %v", w, w.Timeout, err)
+		}
+		time.Sleep(w.Interval)
+	}
+}
+
+// waitForConditions waits for all conditions at the same time, and reports every one
+// that didn't become ready in time
+func waitForConditions(conditions []WaitCondition) error {
+	errs := make([]error, len(conditions))
+	var waiting sync.WaitGroup
+	for i := range conditions {
+		waiting.Add(1)
+		go func() {
+			defer waiting.Done()
+			errs[i] = conditions[i].wait()
+		}()
+	}
+	waiting.Wait()
+
+	var problems []string
+	for _, err := range errs {
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("dependencies not ready: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Package main provides the ProxyLauncher utility
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Exit codes of the launcher when a watchdog killed the target
+const (
+	exitCodeIdleTimeout = 124 // like timeout(1)
+	exitCodeMaxRSS      = 123
+)
+
+// maxWatchdogInterval is how often the watchdog checks the target at most
+const maxWatchdogInterval = time.Second
+
+// watchdogError reports that a watchdog killed the target, and why
+type watchdogError struct {
+	reason   string
+	exitCode int
+}
+
+func (e *watchdogError) Error() string {
+	return "target killed: " + e.reason
+}
+
+// watchdog kills the target when it produces no output for too long or its process tree
+// uses too much memory
+type watchdog struct {
+	idleTimeout time.Duration
+	maxRSS      int64
+	lastOutput  atomic.Int64 // Unix nanoseconds of the latest output
+
+	stopOnce sync.Once
+	stopped  chan struct{}
+	done     chan struct{}
+	killed   *watchdogError
+}
+
+// newWatchdog creates a watchdog for the configured limits, or returns nil if none is configured
+func newWatchdog(config *Configuration) *watchdog {
+	maxRSS := config.MaxRSS
+	if maxRSS > 0 && !processTreeRSSSupported {
+		logf("maxRSS is only supported on Linux, ignoring it")
+		maxRSS = 0
+	}
+	if config.IdleTimeout <= 0 && maxRSS <= 0 {
+		return nil
+	}
+	w := &watchdog{
+		idleTimeout: config.IdleTimeout,
+		maxRSS:      maxRSS,
+		stopped:     make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	w.lastOutput.Store(time.Now().UnixNano())
+	return w
+}
+
+// watchOutput returns a writer passing output on and recording that there was some. Without
+// an idle timeout, the output is left alone, so a terminal is still handed to the target.
+func (w *watchdog) watchOutput(out io.Writer) io.Writer {
+	if w.idleTimeout <= 0 {
+		return out
+	}
+	return &activityWriter{out: out, lastWrite: &w.lastOutput}
+}
+
+// start begins watching the started target
+func (w *watchdog) start(process *os.Process) {
+	interval := maxWatchdogInterval
+	if w.idleTimeout > 0 {
+		interval = min(interval, w.idleTimeout/4)
+	}
+	w.lastOutput.Store(time.Now().UnixNano())
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stopped:
+				return
+			case <-ticker.C:
+			}
+
+			if killed := w.check(process.Pid); killed != nil {
+				// Once the target exited, only processes it left behind can keep its output
+				// open, which the launcher stops waiting for after the idle timeout
+				if errors.Is(killProcessTree(process), os.ErrProcessDone) {
+					return
+				}
+				logf("%v", killed)
+				w.killed = killed
+				return
+			}
+		}
+	}()
+}
+
+// check returns why the target has to be killed, or nil if it may go on
+func (w *watchdog) check(pid int) *watchdogError {
+	if w.idleTimeout > 0 {
+		if idle := time.Since(time.Unix(0, w.lastOutput.Load())); idle >= w.idleTimeout {
+			return &watchdogError{
+				reason:   fmt.Sprintf("no output for %v, exceeding idleTimeout", idle.Round(time.Second)),
+				exitCode: exitCodeIdleTimeout,
+			}
+		}
+	}
+	if w.maxRSS > 0 {
+		if rss := processTreeRSS(pid); rss > w.maxRSS {
+			return &watchdogError{
+				reason:   fmt.Sprintf("resident memory of %d bytes, exceeding maxRSS of %d bytes", rss, w.maxRSS),
+				exitCode: exitCodeMaxRSS,
+			}
+		}
+	}
+	return nil
+}
+
+// stop ends watching once the target has exited and returns the error describing why the
+// watchdog killed it, or nil if it didn't
+func (w *watchdog) stop() error {
+	w.stopOnce.Do(func() { close(w.stopped) })
+	<-w.done
+	if w.killed == nil {
+		return nil
+	}
+	return w.killed
+}
+
+// activityWriter passes writes on and records when the latest one happened
+type activityWriter struct {
+	out       io.Writer
+	lastWrite *atomic.Int64
+}
+
+func (a *activityWriter) Write(p []byte) (int, error) {
+	a.lastWrite.Store(time.Now().UnixNano())
+	return a.out.Write(p)
+}
@@ -0,0 +1,52 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// processTreeRSSSupported tells whether processTreeRSS can measure memory usage
+const processTreeRSSSupported = true
+
+// processTreeRSS returns the summed resident memory in bytes of a process and all its descendants
+func processTreeRSS(pid int) int64 {
+	var total int64
+	for _, member := range append([]int{pid}, descendantsOf(pid)...) {
+		status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", member))
+		if err != nil {
+			continue // exited meanwhile
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(status))
+		for scanner.Scan() {
+			// Like "VmRSS:	  123456 kB"
+			fields := bytes.Fields(scanner.Bytes())
+			if len(fields) == 3 && string(fields[0]) == "VmRSS:" {
+				if kilobytes, err := strconv.ParseInt(string(fields[1]), 10, 64); err == nil {
+					total += kilobytes * 1024
+				}
+				break
+			}
+		}
+	}
+	return total
+}
+
+// killProcessTree kills a process and all of its descendants, returning os.ErrProcessDone
+// if the process already exited
+func killProcessTree(process *os.Process) error {
+	// Collect the tree first, killing the process reparents its children
+	descendants := descendantsOf(process.Pid)
+	err := process.Kill()
+	for _, pid := range descendants {
+		_ = unix.Kill(pid, unix.SIGKILL)
+	}
+	return err
+}
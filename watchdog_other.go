@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "os"
+
+// processTreeRSSSupported tells whether processTreeRSS can measure memory usage
+const processTreeRSSSupported = false
+
+// processTreeRSS can't measure memory usage on this platform
+func processTreeRSS(pid int) int64 {
+	return 0
+}
+
+// killProcessTree kills the process, its descendants can't be found on this platform. It
+// returns os.ErrProcessDone if the process already exited.
+func killProcessTree(process *os.Process) error {
+	return process.Kill()
+}